@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+const xerrorPath = "github.com/olongfen/toolkit/multi/xerror"
+
+var goTemplate = template.Must(template.New("go").Funcs(template.FuncMap{
+	"quote": strconv.Quote,
+}).Parse(`// Code generated by xerrgen from {{.Source}}. DO NOT EDIT.
+
+package {{.Spec.Package}}
+
+import (
+	"context"
+{{- if .Q}}
+	"{{.XerrorPath}}"
+{{- end}}
+)
+{{if .Spec.Messages}}
+const (
+{{- range .Spec.Messages}}
+	// Message{{.Name}} {{.Comment}}
+	Message{{.Name}} {{$.Q}}MessageID = {{quote ($.ID .Name)}}
+{{- end}}
+)
+{{end}}
+{{- if .Spec.Errors}}
+const (
+{{- range .Spec.Errors}}
+	// {{.Name}} {{.Comment}}
+	{{.Name}} = {{.Code}}
+{{- end}}
+)
+{{end}}
+func init() {
+{{- if .Spec.Errors}}
+	{{.Q}}Register(
+{{- range .Definitions}}
+		{{.}},
+{{- end}}
+	)
+{{- end}}
+{{- range .Locales}}
+	if err := {{$.Q}}DefaultErrorMul.Bundle().ParseMessageFileBytes([]byte({{quote .Content}}), {{quote .File}}); err != nil {
+		panic(err)
+	}
+{{- end}}
+}
+{{range .Spec.Errors}}
+// New{{.Name}} new {{.Name}} in the language of ctx{{if .Comment}}, {{.Comment}}{{end}}
+func New{{.Name}}(ctx context.Context, args ...{{$.Q}}Args) {{$.Q}}BizError {
+	return {{$.Q}}NewErrorContextSkip(ctx, 1, {{.Name}}, args...)
+}
+{{end}}`))
+
+type goData struct {
+	Source     string
+	Spec       *Spec
+	Q          string
+	XerrorPath string
+	// Locales message files registered in go code when messages are embedded
+	Locales []locale
+}
+
+type locale struct {
+	File    string
+	Content string
+}
+
+// ID message id of name
+func (d goData) ID(name string) string {
+	return d.Spec.Namespace + "." + name
+}
+
+// Definitions xerror.Definition literals of errors
+func (d goData) Definitions() []string {
+	ret := make([]string, 0, len(d.Spec.Errors))
+	for _, e := range d.Spec.Errors {
+		grpc, _ := e.grpcCode()
+		fields := []string{
+			"Code: " + e.Name,
+			"Namespace: " + strconv.Quote(d.Spec.Namespace),
+			"Name: " + strconv.Quote(e.Name),
+		}
+		if e.HTTPStatus != 0 {
+			fields = append(fields, fmt.Sprintf("HTTPStatus: %d", e.HTTPStatus))
+		}
+		if grpc != 0 {
+			fields = append(fields, fmt.Sprintf("GRPCCode: %d", grpc))
+		}
+		fields = append(fields, "Severity: "+d.Q+severities[e.Severity])
+		if e.Retryable {
+			fields = append(fields, "Retryable: true")
+		}
+		ret = append(ret, d.Q+"Definition{"+strings.Join(fields, ", ")+"}")
+	}
+	return ret
+}
+
+// GenerateGo constants, registrations and constructors, embed puts the message files with every plural form
+// in the go code instead of relying on message files
+func GenerateGo(spec *Spec, source string, embed bool) ([]byte, error) {
+	data := goData{Source: source, Spec: spec, XerrorPath: xerrorPath}
+	if spec.Package != "xerror" {
+		data.Q = "xerror."
+	}
+	if embed {
+		files, err := GenerateLocales(spec)
+		if err != nil {
+			return nil, err
+		}
+		for _, lang := range spec.Languages() {
+			data.Locales = append(data.Locales, locale{File: lang + ".json", Content: string(files[lang])})
+		}
+	}
+	var buf bytes.Buffer
+	if err := goTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	b, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated code: %w\n%s", err, buf.Bytes())
+	}
+	return b, nil
+}
+
+// GenerateLocales go-i18n message file of every language, language -> json
+func GenerateLocales(spec *Spec) (map[string][]byte, error) {
+	ret := map[string][]byte{}
+	for _, lang := range spec.Languages() {
+		messages := map[string]interface{}{}
+		for _, e := range append(append([]Entry{}, spec.Messages...), spec.Errors...) {
+			m, ok := e.message(lang)
+			if !ok {
+				continue
+			}
+			if len(m) == 1 {
+				messages[e.Name] = m.Other()
+			} else {
+				messages[e.Name] = map[string]string(m)
+			}
+		}
+		b, err := marshalJSON(map[string]interface{}{spec.Namespace: messages})
+		if err != nil {
+			return nil, err
+		}
+		ret[lang] = b
+	}
+	return ret, nil
+}
+
+// CatalogueEntry entry of the json catalogue, fields as in xerror.Definition
+type CatalogueEntry struct {
+	Code       int               `json:"code"`
+	Namespace  string            `json:"namespace"`
+	Name       string            `json:"name"`
+	HTTPStatus int               `json:"http_status,omitempty"`
+	GRPCCode   uint32            `json:"grpc_code,omitempty"`
+	Severity   string            `json:"severity"`
+	Retryable  bool              `json:"retryable"`
+	Messages   map[string]string `json:"messages,omitempty"`
+}
+
+func catalogue(spec *Spec) []CatalogueEntry {
+	ret := make([]CatalogueEntry, 0, len(spec.Errors))
+	for _, e := range spec.Errors {
+		grpc, _ := e.grpcCode()
+		entry := CatalogueEntry{
+			Code:       e.Code,
+			Namespace:  spec.Namespace,
+			Name:       e.Name,
+			HTTPStatus: e.HTTPStatus,
+			GRPCCode:   grpc,
+			Severity:   e.Severity,
+			Retryable:  e.Retryable,
+			Messages:   map[string]string{},
+		}
+		if entry.Severity == "" {
+			entry.Severity = "info"
+		}
+		for lang, m := range e.Messages {
+			entry.Messages[canonical(lang)] = m.Other()
+		}
+		ret = append(ret, entry)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Code < ret[j].Code
+	})
+	return ret
+}
+
+// GenerateCatalogueJSON error catalogue for api consumers
+func GenerateCatalogueJSON(spec *Spec) ([]byte, error) {
+	return marshalJSON(catalogue(spec))
+}
+
+// GenerateCatalogueMarkdown error catalogue for api consumers
+func GenerateCatalogueMarkdown(spec *Spec, source string) []byte {
+	var (
+		buf   bytes.Buffer
+		langs = spec.Languages()
+	)
+	grpcNames := map[uint32]string{}
+	for name, c := range grpcCodes {
+		grpcNames[c] = name
+	}
+	fmt.Fprintf(&buf, "<!-- Code generated by xerrgen from %s. DO NOT EDIT. -->\n\n", source)
+	fmt.Fprintf(&buf, "# %s error codes\n\n", spec.Namespace)
+	buf.WriteString("| Code | Name | HTTP | gRPC | Severity | Retryable |")
+	for _, lang := range langs {
+		buf.WriteString(" " + lang + " |")
+	}
+	buf.WriteString("\n|---|---|---|---|---|---|" + strings.Repeat("---|", len(langs)) + "\n")
+	for _, e := range catalogue(spec) {
+		grpc := ""
+		if e.GRPCCode != 0 {
+			grpc = grpcNames[e.GRPCCode]
+			if grpc == "" {
+				grpc = strconv.Itoa(int(e.GRPCCode))
+			}
+		}
+		status := ""
+		if e.HTTPStatus != 0 {
+			status = strconv.Itoa(e.HTTPStatus)
+		}
+		fmt.Fprintf(&buf, "| %d | %s.%s | %s | %s | %s | %t |", e.Code, e.Namespace, e.Name, status, grpc, e.Severity, e.Retryable)
+		for _, lang := range langs {
+			buf.WriteString(" " + strings.ReplaceAll(e.Messages[lang], "|", `\|`) + " |")
+		}
+		buf.WriteString("\n")
+	}
+	return buf.Bytes()
+}
+
+func marshalJSON(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeFile write file creating its directory
+func writeFile(filename string, b []byte) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, b, 0o644)
+}
@@ -0,0 +1,66 @@
+// Command xerrgen generate xerror codes, constructors, go-i18n message files and an error catalogue from errors.yaml
+//
+//	//go:generate go run github.com/olongfen/toolkit/cmd/xerrgen -spec errors.yaml
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	var (
+		specFile  = flag.String("spec", "errors.yaml", "error spec")
+		goFile    = flag.String("go", "errors_gen.go", "generated go file, relative to the spec directory")
+		locales   = flag.String("locales", "locales", "directory of generated message files, empty to skip")
+		catalogue = flag.String("catalogue", "errors", "base name of the generated .md and .json catalogue, empty to skip")
+		embed     = flag.Bool("embed-messages", true, "register messages in go code, disable when the message files are loaded instead")
+	)
+	flag.Parse()
+	if err := run(*specFile, *goFile, *locales, *catalogue, *embed); err != nil {
+		fmt.Fprintln(os.Stderr, "xerrgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(specFile, goFile, locales, catalogue string, embed bool) error {
+	spec, err := LoadSpec(specFile)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(specFile)
+	source := filepath.Base(specFile)
+	b, err := GenerateGo(spec, source, embed)
+	if err != nil {
+		return err
+	}
+	if err = writeFile(filepath.Join(dir, goFile), b); err != nil {
+		return err
+	}
+	if locales != "" {
+		files, err := GenerateLocales(spec)
+		if err != nil {
+			return err
+		}
+		for lang, b := range files {
+			if err = writeFile(filepath.Join(dir, locales, lang+".json"), b); err != nil {
+				return err
+			}
+		}
+	}
+	if catalogue != "" {
+		b, err := GenerateCatalogueJSON(spec)
+		if err != nil {
+			return err
+		}
+		if err = writeFile(filepath.Join(dir, catalogue+".json"), b); err != nil {
+			return err
+		}
+		if err = writeFile(filepath.Join(dir, catalogue+".md"), GenerateCatalogueMarkdown(spec, source)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+	b, err := os.ReadFile("testdata/errors.yaml")
+	require.NoError(t, err)
+	spec := filepath.Join(dir, "errors.yaml")
+	require.NoError(t, os.WriteFile(spec, b, 0o644))
+	require.NoError(t, run(spec, "errors_gen.go", "locales", "errors", true))
+
+	code, err := os.ReadFile(filepath.Join(dir, "errors_gen.go"))
+	require.NoError(t, err)
+	f, err := parser.ParseFile(token.NewFileSet(), "errors_gen.go", code, parser.ImportsOnly)
+	require.NoError(t, err)
+	assert.Equal(t, "billing", f.Name.Name)
+	assert.Contains(t, string(code), `"github.com/olongfen/toolkit/multi/xerror"`)
+	assert.Contains(t, string(code), "InvoiceNotFound = 44001")
+	assert.Contains(t, string(code), `MessageInvoicePaid xerror.MessageID = "billing.InvoicePaid"`)
+	assert.Contains(t, string(code), `xerror.Definition{Code: TooManyItems, Namespace: "billing", Name: "TooManyItems", HTTPStatus: 422, GRPCCode: 8, Severity: xerror.SeverityError, Retryable: true}`)
+	assert.Contains(t, string(code), `xerror.DefaultErrorMul.Bundle().ParseMessageFileBytes([]byte(`)
+	assert.Contains(t, string(code), `\"one\": \"max {{.PluralCount}} item | allowed\"`)
+	assert.Contains(t, string(code), `"zh-CN.json"`)
+	assert.Contains(t, string(code), "func NewInvoiceNotFound(ctx context.Context, args ...xerror.Args) xerror.BizError {")
+
+	en, err := os.ReadFile(filepath.Join(dir, "locales", "en.json"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"billing": {"InvoicePaid": "invoice paid", "InvoiceNotFound": "invoice {{.ID}} not found", "TooManyItems": {"one": "max {{.PluralCount}} item | allowed", "other": "max {{.PluralCount}} items allowed"}}}`, string(en))
+	_, err = os.Stat(filepath.Join(dir, "locales", "zh-CN.json"))
+	assert.NoError(t, err)
+
+	catalogue, err := os.ReadFile(filepath.Join(dir, "errors.json"))
+	require.NoError(t, err)
+	assert.Contains(t, string(catalogue), `"grpc_code": 5`)
+	md, err := os.ReadFile(filepath.Join(dir, "errors.md"))
+	require.NoError(t, err)
+	assert.Contains(t, string(md), "| 44001 | billing.InvoiceNotFound | 404 | NotFound | info | false | invoice {{.ID}} not found | 发票 {{.ID}} 不存在 |")
+	assert.Contains(t, string(md), `max {{.PluralCount}} items allowed |  |`)
+}
+
+// TestXerrorUpToDate the generated files of multi/xerror match errors.yaml
+func TestXerrorUpToDate(t *testing.T) {
+	const dir = "../../multi/xerror"
+	spec, err := LoadSpec(filepath.Join(dir, "errors.yaml"))
+	require.NoError(t, err)
+	code, err := GenerateGo(spec, "errors.yaml", false)
+	require.NoError(t, err)
+	assertFile(t, filepath.Join(dir, "errors_gen.go"), code)
+	files, err := GenerateLocales(spec)
+	require.NoError(t, err)
+	for lang, b := range files {
+		assertFile(t, filepath.Join(dir, "locales", lang+".json"), b)
+	}
+	b, err := GenerateCatalogueJSON(spec)
+	require.NoError(t, err)
+	assertFile(t, filepath.Join(dir, "errors.json"), b)
+	assertFile(t, filepath.Join(dir, "errors.md"), GenerateCatalogueMarkdown(spec, "errors.yaml"))
+}
+
+func assertFile(t *testing.T, filename string, expect []byte) {
+	t.Helper()
+	b, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	assert.Equal(t, string(expect), string(b), "%s is out of date, run go generate ./multi/xerror", filename)
+}
+
+func TestSpecValidate(t *testing.T) {
+	cases := map[string]string{
+		"package: x-y\nnamespace: a": "invalid package",
+		"package: x":                 "namespace is required",
+		"package: x\nnamespace: a\nerrors: [{name: A, code: 1}, {name: B, code: 1}]":       "code 1 of B already used by A",
+		"package: x\nnamespace: a\nerrors: [{name: a, code: 1}]":                           "invalid name",
+		"package: x\nnamespace: a\nerrors: [{name: A, code: 1, grpc_code: Nope}]":          "invalid grpc code",
+		"package: x\nnamespace: a\nerrors: [{name: A, code: 1, severity: fatal}]":          "invalid severity",
+		"package: x\nnamespace: a\nerrors: [{name: A, code: 1, messages: {en: {one: x}}}]": "no other form",
+		"package: x\nnamespace: a\nmessages: [{name: A}]\nerrors: [{name: A, code: 2}]":    "duplicate name A",
+		"package: x\nnamespace: a\nmessages: [{name: A, code: -2}]":                        "message A has a code",
+	}
+	dir := t.TempDir()
+	for content, msg := range cases {
+		spec := filepath.Join(dir, "errors.yaml")
+		require.NoError(t, os.WriteFile(spec, []byte(content), 0o644))
+		_, err := LoadSpec(spec)
+		if assert.Error(t, err, content) {
+			assert.True(t, strings.Contains(err.Error(), msg), err.Error())
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"golang.org/x/text/language"
+	"gopkg.in/yaml.v3"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// grpcCodes google.golang.org/grpc/codes names
+var grpcCodes = map[string]uint32{
+	"OK":                 0,
+	"Canceled":           1,
+	"Unknown":            2,
+	"InvalidArgument":    3,
+	"DeadlineExceeded":   4,
+	"NotFound":           5,
+	"AlreadyExists":      6,
+	"PermissionDenied":   7,
+	"ResourceExhausted":  8,
+	"FailedPrecondition": 9,
+	"Aborted":            10,
+	"OutOfRange":         11,
+	"Unimplemented":      12,
+	"Internal":           13,
+	"Unavailable":        14,
+	"DataLoss":           15,
+	"Unauthenticated":    16,
+}
+
+var severities = map[string]string{
+	"":         "SeverityInfo",
+	"info":     "SeverityInfo",
+	"warning":  "SeverityWarning",
+	"error":    "SeverityError",
+	"critical": "SeverityCritical",
+}
+
+// Spec errors.yaml
+type Spec struct {
+	// Package go package of the generated file
+	Package string `yaml:"package"`
+	// Namespace namespace of every code
+	Namespace string `yaml:"namespace"`
+	// Messages envelope messages, only xerror.MessageID constants and translations are generated, messages have no code
+	Messages []Entry `yaml:"messages"`
+	Errors   []Entry `yaml:"errors"`
+}
+
+// Entry error code or envelope message
+type Entry struct {
+	Name       string `yaml:"name"`
+	Code       int    `yaml:"code"`
+	Comment    string `yaml:"comment"`
+	HTTPStatus int    `yaml:"http_status"`
+	// GRPCCode name such as "NotFound" or number
+	GRPCCode  string `yaml:"grpc_code"`
+	Severity  string `yaml:"severity"`
+	Retryable bool   `yaml:"retryable"`
+	// Messages language -> message, a message is a template string or plural forms {one: ..., other: ...}
+	Messages map[string]Message `yaml:"messages"`
+}
+
+// Message message template, Other is used when no plural form is given
+type Message map[string]string
+
+// UnmarshalYAML accept a string as the "other" form
+func (m *Message) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*m = Message{"other": node.Value}
+		return nil
+	}
+	var forms map[string]string
+	if err := node.Decode(&forms); err != nil {
+		return err
+	}
+	*m = forms
+	return nil
+}
+
+// Other default form
+func (m Message) Other() string {
+	return m["other"]
+}
+
+// LoadSpec load and validate spec
+func LoadSpec(filename string) (*Spec, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var spec Spec
+	if err = yaml.Unmarshal(b, &spec); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	if err = spec.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return &spec, nil
+}
+
+func (s *Spec) validate() error {
+	if !token.IsIdentifier(s.Package) {
+		return fmt.Errorf("invalid package %q", s.Package)
+	}
+	if s.Namespace == "" {
+		return fmt.Errorf("namespace is required")
+	}
+	var (
+		codes = map[int]string{}
+		names = map[string]bool{}
+	)
+	for _, e := range append(append([]Entry{}, s.Messages...), s.Errors...) {
+		if !token.IsIdentifier(e.Name) || !token.IsExported(e.Name) {
+			return fmt.Errorf("invalid name %q", e.Name)
+		}
+		// 消息与错误码共用 namespace.name 消息 id
+		if names[e.Name] {
+			return fmt.Errorf("duplicate name %s", e.Name)
+		}
+		names[e.Name] = true
+		for lang, m := range e.Messages {
+			if _, err := language.Parse(lang); err != nil {
+				return fmt.Errorf("%s: invalid language %q", e.Name, lang)
+			}
+			if m.Other() == "" {
+				return fmt.Errorf("%s: message of %s has no other form", e.Name, lang)
+			}
+		}
+	}
+	for _, e := range s.Messages {
+		if e.Code != 0 {
+			return fmt.Errorf("message %s has a code, envelope messages are not error codes", e.Name)
+		}
+	}
+	for _, e := range s.Errors {
+		if name, ok := codes[e.Code]; ok {
+			return fmt.Errorf("code %d of %s already used by %s", e.Code, e.Name, name)
+		}
+		codes[e.Code] = e.Name
+		if _, err := e.grpcCode(); err != nil {
+			return fmt.Errorf("%s: %w", e.Name, err)
+		}
+		if _, ok := severities[e.Severity]; !ok {
+			return fmt.Errorf("%s: invalid severity %q", e.Name, e.Severity)
+		}
+	}
+	return nil
+}
+
+func (e Entry) grpcCode() (uint32, error) {
+	if e.GRPCCode == "" {
+		return 0, nil
+	}
+	if c, ok := grpcCodes[e.GRPCCode]; ok {
+		return c, nil
+	}
+	c, err := strconv.ParseUint(e.GRPCCode, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid grpc code %q", e.GRPCCode)
+	}
+	return uint32(c), nil
+}
+
+// Languages every language of messages, canonical and sorted
+func (s *Spec) Languages() []string {
+	set := map[string]bool{}
+	for _, e := range append(append([]Entry{}, s.Messages...), s.Errors...) {
+		for lang := range e.Messages {
+			set[canonical(lang)] = true
+		}
+	}
+	ret := make([]string, 0, len(set))
+	for lang := range set {
+		ret = append(ret, lang)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// message of entry in canonical lang
+func (e Entry) message(lang string) (Message, bool) {
+	for k, m := range e.Messages {
+		if canonical(k) == lang {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
+func canonical(lang string) string {
+	return language.Make(lang).String()
+}
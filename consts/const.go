@@ -5,3 +5,13 @@ const (
 	TraditionalChinese = "zh-tw"
 	English            = "en"
 )
+
+// SupportedLanguages languages negotiated by default, append to support more
+var SupportedLanguages = []string{SimplifiedChinese, TraditionalChinese, English}
+
+const (
+	// HeaderRequestID request id header
+	HeaderRequestID = "X-Request-ID"
+	// HeaderTraceID trace id header
+	HeaderTraceID = "X-Trace-ID"
+)
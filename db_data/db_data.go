@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"github.com/olongfen/toolkit/multi/xerror"
-	"github.com/olongfen/toolkit/scontext"
 	"github.com/olongfen/toolkit/tools"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
@@ -178,9 +177,9 @@ func after(db *gorm.DB) {
 }
 
 func handlerDBError(db *gorm.DB) {
-	lang := scontext.GetLanguage(db.Statement.Context)
+	ctx := db.Statement.Context
 	if errors.Is(db.Error, gorm.ErrRecordNotFound) {
-		db.Error = xerror.NewError(xerror.RecordNotFound, lang)
+		db.Error = xerror.NewErrorContext(ctx, xerror.RecordNotFound).WithError(db.Error)
 		return
 	}
 	if db.Statement.Schema == nil {
@@ -196,7 +195,7 @@ func handlerDBError(db *gorm.DB) {
 			field := db.Statement.Schema.FieldsByDBName[v]
 			name := strings.ToLower(field.Name[:1]) + field.Name[1:]
 			var errs = xerror.DBErrorResponse{}
-			errs[name] = xerror.NewError(xerror.AlreadyExists, lang)
+			errs[name] = xerror.NewErrorContext(ctx, xerror.AlreadyExists)
 			db.Error = errs
 		}
 	}
@@ -0,0 +1,370 @@
+package i18n
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/BurntSushi/toml"
+	goi18n "github.com/nicksnyder/go-i18n/v2/i18n"
+	"github.com/olongfen/toolkit/consts"
+	"golang.org/x/text/language"
+	"gopkg.in/yaml.v3"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+type (
+	// Message translatable message, Other is used when no plural form matches
+	Message = goi18n.Message
+	// LocalizeConfig message id, template data and plural count of a localization
+	LocalizeConfig = goi18n.LocalizeConfig
+)
+
+var (
+	// unmarshalFuncs message file formats by extension
+	unmarshalFuncs = map[string]goi18n.UnmarshalFunc{
+		"json": json.Unmarshal,
+		"yaml": yaml.Unmarshal,
+		"yml":  yaml.Unmarshal,
+		"toml": toml.Unmarshal,
+	}
+	// ErrUnsupportedFormat file extension is not json, yaml, yml or toml
+	ErrUnsupportedFormat = errors.New("i18n: unsupported message file format")
+)
+
+// Bundle go-i18n bundle remembering every loaded message, safe for concurrent use
+type Bundle struct {
+	mu              sync.RWMutex
+	defaultLanguage language.Tag
+	bundle          *goi18n.Bundle
+	matcher         language.Matcher
+	// messages language -> id -> message
+	messages map[language.Tag]map[string]*Message
+	// fallbacks language -> languages tried in turn when it has no translation
+	fallbacks map[language.Tag][]language.Tag
+}
+
+// NewBundle new bundle, defaultLanguage is used when the requested language has no translation
+func NewBundle(defaultLanguage string) *Bundle {
+	tag := tagOf(defaultLanguage)
+	b := &Bundle{
+		defaultLanguage: tag,
+		messages:        map[language.Tag]map[string]*Message{},
+		fallbacks:       map[language.Tag][]language.Tag{},
+	}
+	b.bundle = b.newBundle()
+	b.matcher = language.NewMatcher(b.bundle.LanguageTags())
+	return b
+}
+
+func (b *Bundle) newBundle() *goi18n.Bundle {
+	bundle := goi18n.NewBundle(b.defaultLanguage)
+	for format, fn := range unmarshalFuncs {
+		bundle.RegisterUnmarshalFunc(format, fn)
+	}
+	return bundle
+}
+
+// DefaultLanguage default language of bundle
+func (b *Bundle) DefaultLanguage() string {
+	return Normalize(b.defaultLanguage.String())
+}
+
+// SetFallback set languages tried in turn when lang has no translation, before the default language
+func (b *Bundle) SetFallback(lang string, fallbacks ...string) *Bundle {
+	tags := make([]language.Tag, 0, len(fallbacks))
+	for _, v := range fallbacks {
+		tags = append(tags, tagOf(v))
+	}
+	b.mu.Lock()
+	b.fallbacks[tagOf(lang)] = tags
+	b.mu.Unlock()
+	return b
+}
+
+// chain languages tried in turn for lang: the matched language, its fallbacks, the default language and its fallbacks
+func (b *Bundle) chain(lang string) []language.Tag {
+	var (
+		ret  []language.Tag
+		seen = map[language.Tag]bool{}
+	)
+	var add func(tag language.Tag)
+	add = func(tag language.Tag) {
+		if seen[tag] {
+			return
+		}
+		seen[tag] = true
+		ret = append(ret, tag)
+		for _, v := range b.fallbacks[tag] {
+			add(v)
+		}
+	}
+	add(b.match(lang))
+	add(b.defaultLanguage)
+	return ret
+}
+
+// LoadMessageFile load json, yaml or toml message file, the language is taken from the file name, e.g. "en.yaml" or "active.zh-CN.json"
+func (b *Bundle) LoadMessageFile(filename string) error {
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	return b.ParseMessageFileBytes(buf, filename)
+}
+
+// LoadFS load every message file in dir of fsys, usually an embed.FS
+func (b *Bundle) LoadFS(fsys fs.FS, dir string) error {
+	return fs.WalkDir(fsys, dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !IsMessageFile(p) {
+			return err
+		}
+		buf, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		return b.ParseMessageFileBytes(buf, p)
+	})
+}
+
+// IsMessageFile filename has a supported extension
+func IsMessageFile(filename string) bool {
+	_, ok := unmarshalFuncs[strings.TrimPrefix(path.Ext(filename), ".")]
+	return ok
+}
+
+// ParseMessageFileBytes parse and add messages of file content, filename decides language and format
+func (b *Bundle) ParseMessageFileBytes(buf []byte, filename string) error {
+	if !IsMessageFile(filename) {
+		return ErrUnsupportedFormat
+	}
+	file, err := goi18n.ParseMessageFileBytes(buf, filename, unmarshalFuncs)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.addMessages(tagOf(file.Tag.String()), file.Messages...)
+}
+
+// AddMessages add messages of lang, a message without any content removes the translation
+func (b *Bundle) AddMessages(lang string, messages ...*Message) error {
+	if _, err := language.Parse(lang); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.addMessages(tagOf(lang), messages...)
+}
+
+func (b *Bundle) addMessages(tag language.Tag, messages ...*Message) error {
+	if err := b.bundle.AddMessages(tag, messages...); err != nil {
+		return err
+	}
+	if b.messages[tag] == nil {
+		b.messages[tag] = map[string]*Message{}
+		b.matcher = language.NewMatcher(b.bundle.LanguageTags())
+	}
+	for _, m := range messages {
+		if goi18n.NewMessageTemplate(m) == nil {
+			delete(b.messages[tag], m.ID)
+			continue
+		}
+		b.messages[tag][m.ID] = m
+	}
+	return nil
+}
+
+// Clone deep copy of bundle, load into the clone and swap it in so readers never see a half-loaded bundle
+func (b *Bundle) Clone() *Bundle {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	c := &Bundle{
+		defaultLanguage: b.defaultLanguage,
+		messages:        map[language.Tag]map[string]*Message{},
+		fallbacks:       map[language.Tag][]language.Tag{},
+	}
+	c.bundle = c.newBundle()
+	for tag, messages := range b.messages {
+		list := make([]*Message, 0, len(messages))
+		for _, m := range messages {
+			cp := *m
+			list = append(list, &cp)
+		}
+		_ = c.addMessages(tag, list...)
+	}
+	for tag, fallbacks := range b.fallbacks {
+		c.fallbacks[tag] = append([]language.Tag(nil), fallbacks...)
+	}
+	c.matcher = language.NewMatcher(c.bundle.LanguageTags())
+	return c
+}
+
+// RemoveMessage remove message id of every language
+func (b *Bundle) RemoveMessage(id string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// go-i18n 不支持删除, 用剩余的消息重建
+	bundle := b.newBundle()
+	for tag, messages := range b.messages {
+		delete(messages, id)
+		list := make([]*Message, 0, len(messages))
+		for _, m := range messages {
+			list = append(list, m)
+		}
+		_ = bundle.AddMessages(tag, list...)
+	}
+	b.bundle = bundle
+	b.matcher = language.NewMatcher(bundle.LanguageTags())
+}
+
+// Localize localize message in lang, the fallbacks of lang then the default language are used when lang has no translation
+func (b *Bundle) Localize(lang string, config *LocalizeConfig) (string, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	id := config.MessageID
+	if config.DefaultMessage != nil {
+		id = config.DefaultMessage.ID
+	}
+	for _, tag := range b.chain(lang) {
+		if _, ok := b.messages[tag][id]; !ok {
+			continue
+		}
+		return goi18n.NewLocalizer(b.bundle, tag.String()).Localize(config)
+	}
+	return goi18n.NewLocalizer(b.bundle, b.defaultLanguage.String()).Localize(config)
+}
+
+// Lookup message of id in lang, the fallbacks of lang then the default language are used when lang has no translation
+func (b *Bundle) Lookup(lang, id string) (*Message, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, tag := range b.chain(lang) {
+		if m, ok := b.messages[tag][id]; ok {
+			cp := *m
+			return &cp, true
+		}
+	}
+	return nil, false
+}
+
+// match best language of bundle, x/text falls back to english for unknown languages so the default language is used instead
+func (b *Bundle) match(lang string) language.Tag {
+	tags := b.bundle.LanguageTags()
+	_, i, conf := b.matcher.Match(language.Make(lang))
+	if conf == language.No || i >= len(tags) {
+		return b.defaultLanguage
+	}
+	return tags[i]
+}
+
+// Messages copy of every message, id -> language -> message
+func (b *Bundle) Messages() map[string]map[string]*Message {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	ret := map[string]map[string]*Message{}
+	for tag, messages := range b.messages {
+		lang := Normalize(tag.String())
+		for id, m := range messages {
+			if ret[id] == nil {
+				ret[id] = map[string]*Message{}
+			}
+			cp := *m
+			ret[id][lang] = &cp
+		}
+	}
+	return ret
+}
+
+// Missing ids missing a translation in any of languages, id -> missing languages
+func (b *Bundle) Missing(languages ...string) map[string][]string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	ids := map[string]bool{}
+	for _, messages := range b.messages {
+		for id := range messages {
+			ids[id] = true
+		}
+	}
+	ret := map[string][]string{}
+	for id := range ids {
+		for _, lang := range languages {
+			if _, ok := b.messages[tagOf(lang)][id]; !ok {
+				ret[id] = append(ret[id], Normalize(lang))
+			}
+		}
+	}
+	return ret
+}
+
+// Languages languages having messages
+func (b *Bundle) Languages() []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	ret := make([]string, 0, len(b.messages))
+	for tag, messages := range b.messages {
+		if len(messages) > 0 {
+			ret = append(ret, Normalize(tag.String()))
+		}
+	}
+	return ret
+}
+
+// supportedMatcher matcher of consts.SupportedLanguages
+type supportedMatcher struct {
+	languages []string
+	matcher   language.Matcher
+}
+
+// supported cached matcher, rebuilt only when consts.SupportedLanguages changes
+var supported atomic.Pointer[supportedMatcher]
+
+// supportedLanguages matcher of the current consts.SupportedLanguages
+func supportedLanguages() *supportedMatcher {
+	if m := supported.Load(); m != nil && equal(m.languages, consts.SupportedLanguages) {
+		return m
+	}
+	m := &supportedMatcher{languages: append([]string(nil), consts.SupportedLanguages...)}
+	tags := make([]language.Tag, 0, len(m.languages))
+	for _, v := range m.languages {
+		tags = append(tags, language.Make(v))
+	}
+	m.matcher = language.NewMatcher(tags)
+	supported.Store(m)
+	return m
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Normalize normalize BCP-47 language tag to the form of consts and scontext,
+// a tag close to one of consts.SupportedLanguages becomes it, e.g. "zh" "zh_CN" "zh-Hans" -> "zh-cn",
+// other tags are canonicalized in lower case, e.g. "JA-jp" -> "ja-jp"
+func Normalize(lang string) string {
+	tag, err := language.Parse(lang)
+	if err != nil {
+		return strings.ToLower(lang)
+	}
+	m := supportedLanguages()
+	if _, i, conf := m.matcher.Match(tag); conf >= language.High {
+		return strings.ToLower(m.languages[i])
+	}
+	return strings.ToLower(tag.String())
+}
+
+// tagOf tag of normalized lang
+func tagOf(lang string) language.Tag {
+	return language.Make(Normalize(lang))
+}
@@ -0,0 +1,96 @@
+package i18n
+
+import (
+	"github.com/olongfen/toolkit/consts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"testing/fstest"
+)
+
+func TestBundle(t *testing.T) {
+	b := NewBundle("zh-cn")
+	require.NoError(t, b.LoadMessageFile("testdata/en.yaml"))
+	require.NoError(t, b.LoadMessageFile("testdata/active.zh-CN.toml"))
+	assert.ErrorIs(t, b.ParseMessageFileBytes([]byte("x"), "en.txt"), ErrUnsupportedFormat)
+	assert.ElementsMatch(t, []string{"en", "zh-cn"}, b.Languages())
+
+	localize := func(lang string, count int) string {
+		msg, err := b.Localize(lang, &LocalizeConfig{
+			MessageID:    "Items",
+			TemplateData: map[string]interface{}{"Name": "cart", "PluralCount": count},
+			PluralCount:  count,
+		})
+		require.NoError(t, err)
+		return msg
+	}
+	assert.Equal(t, "cart has 1 item", localize("en", 1))
+	assert.Equal(t, "cart has 2 items", localize("en-US", 2))
+	assert.Equal(t, "cart 有 2 个项目", localize("zh-cn", 2))
+	// 未知语言使用默认语言
+	assert.Equal(t, "cart 有 1 个项目", localize("fr", 1))
+
+	m, ok := b.Lookup("fr", "Items")
+	require.True(t, ok)
+	assert.Equal(t, "{{.Name}} 有 {{.PluralCount}} 个项目", m.Other)
+	_, ok = b.Lookup("en", "Missing")
+	assert.False(t, ok)
+
+	msg, err := b.Localize("en", &LocalizeConfig{MessageID: "Missing"})
+	assert.Error(t, err)
+	assert.Empty(t, msg)
+
+	b.RemoveMessage("Hello")
+	msg, _ = b.Localize("en", &LocalizeConfig{MessageID: "Hello"})
+	assert.Empty(t, msg)
+	assert.Equal(t, "cart has 2 items", localize("en", 2))
+	assert.NotContains(t, b.Messages(), "Hello")
+}
+
+func TestLoadFS(t *testing.T) {
+	b := NewBundle("en")
+	fsys := fstest.MapFS{
+		"locales/en.json":        {Data: []byte(`{"Hello": "hello"}`)},
+		"locales/ja.json":        {Data: []byte(`{"Hello": "こんにちは"}`)},
+		"locales/README.md":      {Data: []byte("ignored")},
+		"locales/sub/zh-TW.json": {Data: []byte(`{"Hello": "你好"}`)},
+	}
+	require.NoError(t, b.LoadFS(fsys, "locales"))
+	msg, err := b.Localize("ja", &LocalizeConfig{MessageID: "Hello"})
+	require.NoError(t, err)
+	assert.Equal(t, "こんにちは", msg)
+	msg, _ = b.Localize("zh-Hant", &LocalizeConfig{MessageID: "Hello"})
+	assert.Equal(t, "你好", msg)
+
+	require.NoError(t, b.AddMessages("ja", &Message{ID: "Hello"}))
+	msg, _ = b.Localize("ja", &LocalizeConfig{MessageID: "Hello"})
+	assert.Equal(t, "hello", msg)
+	assert.Len(t, b.Messages()["Hello"], 2)
+}
+
+func TestNormalize(t *testing.T) {
+	cases := map[string]string{
+		"zh":      "zh-cn",
+		"zh_CN":   "zh-cn",
+		"zh-Hans": "zh-cn",
+		"zh-Hant": "zh-tw",
+		"zh-HK":   "zh-tw",
+		"EN":      "en",
+		"en-GB":   "en",
+		"ja-JP":   "ja-jp",
+		"!bad":    "!bad",
+	}
+	for in, out := range cases {
+		assert.Equal(t, out, Normalize(in), in)
+	}
+
+	// 支持的语言变化后重建 matcher
+	old := consts.SupportedLanguages
+	defer func() {
+		consts.SupportedLanguages = old
+	}()
+	consts.SupportedLanguages = append(append([]string(nil), old...), "ja")
+	assert.Equal(t, "ja", Normalize("ja-JP"))
+	consts.SupportedLanguages = old
+	assert.Equal(t, "ja-jp", Normalize("ja-JP"))
+}
@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"errors"
+	fiber "github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/olongfen/toolkit/multi/xerror"
+	"github.com/olongfen/toolkit/scontext"
+	"strings"
+	"time"
+)
+
+// ErrMissingToken request has no token
+var ErrMissingToken = errors.New("auth: missing token")
+
+// AuthConfig authentication middleware config
+type AuthConfig struct {
+	// Next skip middleware when return true
+	Next func(c *fiber.Ctx) bool
+	// Key verification key, []byte for HS, *rsa.PublicKey for RS, *ecdsa.PublicKey for ES
+	Key interface{}
+	// KeySet keys selected by the kid header, take precedence over Key
+	KeySet *JWKS
+	// Methods allowed signing methods, default HS/RS/ES 256/384/512
+	Methods []string
+	// Issuer expected iss, not checked when empty
+	Issuer string
+	// Audience expected aud, not checked when empty
+	Audience string
+	// Leeway allowed clock skew when checking exp, nbf and iat
+	Leeway time.Duration
+	// TokenLookup "header:<name>", "query:<name>" or "cookie:<name>", default "header:Authorization"
+	TokenLookup string
+	// AuthScheme scheme before token in header, default "Bearer"
+	AuthScheme string
+	// Principal build principal from claims, default reads sub, tenant, roles, scope and sid
+	Principal func(c *fiber.Ctx, claims jwt.MapClaims) scontext.Principal
+}
+
+// Authenticate verify jwt of request, on success the subject and claims are stored in
+// ctx.UserContext(), otherwise a localized xerror.IllegalAccessToken is returned
+func Authenticate(config AuthConfig) fiber.Handler {
+	conf := config
+	if len(conf.Methods) == 0 {
+		conf.Methods = []string{"HS256", "HS384", "HS512", "RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}
+	}
+	if conf.TokenLookup == "" {
+		conf.TokenLookup = "header:" + fiber.HeaderAuthorization
+	}
+	if conf.AuthScheme == "" {
+		conf.AuthScheme = "Bearer"
+	}
+	if conf.Principal == nil {
+		conf.Principal = principalFromClaims
+	}
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(conf.Methods),
+		jwt.WithLeeway(conf.Leeway),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	}
+	if conf.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(conf.Issuer))
+	}
+	if conf.Audience != "" {
+		opts = append(opts, jwt.WithAudience(conf.Audience))
+	}
+	parser := jwt.NewParser(opts...)
+	keyFunc := func(token *jwt.Token) (interface{}, error) {
+		if conf.KeySet != nil {
+			kid, _ := token.Header["kid"].(string)
+			return conf.KeySet.Key(kid)
+		}
+		if conf.Key == nil {
+			return nil, ErrKeyNotFound
+		}
+		return conf.Key, nil
+	}
+	lookup := tokenExtractor(conf.TokenLookup, conf.AuthScheme)
+
+	return func(c *fiber.Ctx) error {
+		if conf.Next != nil && conf.Next(c) {
+			return c.Next()
+		}
+		ctx := c.UserContext()
+		raw := lookup(c)
+		if raw == "" {
+			return xerror.NewErrorContext(ctx, xerror.IllegalAccessToken).WithError(ErrMissingToken)
+		}
+		claims := jwt.MapClaims{}
+		if _, err := parser.ParseWithClaims(raw, claims, keyFunc); err != nil {
+			if errors.Is(err, ErrKeyNotFound) {
+				return xerror.NewErrorContext(ctx, xerror.IllegalCertificate).WithError(err)
+			}
+			return xerror.NewErrorContext(ctx, xerror.IllegalAccessToken).WithError(err)
+		}
+		ctx = scontext.SetClaims(ctx, claims)
+		ctx = scontext.SetPrincipal(ctx, conf.Principal(c, claims))
+		c.SetUserContext(ctx)
+		return c.Next()
+	}
+}
+
+func tokenExtractor(lookup, scheme string) func(c *fiber.Ctx) string {
+	parts := strings.SplitN(lookup, ":", 2)
+	if len(parts) != 2 {
+		panic("auth: invalid token lookup " + lookup)
+	}
+	key := strings.TrimSpace(parts[1])
+	switch parts[0] {
+	case "query":
+		return func(c *fiber.Ctx) string {
+			return c.Query(key)
+		}
+	case "cookie":
+		return func(c *fiber.Ctx) string {
+			return c.Cookies(key)
+		}
+	default:
+		return func(c *fiber.Ctx) string {
+			val := c.Get(key)
+			if len(val) > len(scheme) && strings.EqualFold(val[:len(scheme)], scheme) && val[len(scheme)] == ' ' {
+				return strings.TrimSpace(val[len(scheme):])
+			}
+			return ""
+		}
+	}
+}
+
+func principalFromClaims(c *fiber.Ctx, claims jwt.MapClaims) scontext.Principal {
+	p, _ := scontext.GetPrincipal(c.UserContext())
+	p.UserUuid, _ = claims.GetSubject()
+	p.Tenant, _ = claims["tenant"].(string)
+	p.SessionID, _ = claims["sid"].(string)
+	p.Roles = claimStrings(claims["roles"])
+	if scope, ok := claims["scope"].(string); ok {
+		p.Scopes = strings.Fields(scope)
+	} else {
+		p.Scopes = claimStrings(claims["scp"])
+	}
+	p.ClientIP = c.IP()
+	p.UserAgent = c.Get(fiber.HeaderUserAgent)
+	p.RequestID = scontext.GetRequestID(c.UserContext())
+	return p
+}
+
+func claimStrings(v interface{}) []string {
+	switch val := v.(type) {
+	case string:
+		return []string{val}
+	case []interface{}:
+		ret := make([]string, 0, len(val))
+		for _, s := range val {
+			if str, ok := s.(string); ok {
+				ret = append(ret, str)
+			}
+		}
+		return ret
+	case []string:
+		return val
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package middleware
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	fiber "github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/olongfen/toolkit/multi/xerror"
+	"github.com/olongfen/toolkit/response"
+	"github.com/olongfen/toolkit/scontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func b64(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	require.NoError(t, err)
+	return s
+}
+
+func newAuthApp(conf AuthConfig) *fiber.App {
+	app := fiber.New(fiber.Config{ErrorHandler: response.ErrorHandler})
+	app.Use(Language(), Authenticate(conf))
+	app.Get("/", func(c *fiber.Ctx) error {
+		p, _ := scontext.GetPrincipal(c.UserContext())
+		return response.NewResponse().Success(c, map[string]interface{}{
+			"user":   scontext.GetUserUuid(c.UserContext()),
+			"roles":  p.Roles,
+			"claims": scontext.GetClaims(c.UserContext()),
+		})
+	})
+	return app
+}
+
+func doAuth(t *testing.T, app *fiber.App, token string) response.Response {
+	req := httptest.NewRequest(fiber.MethodGet, "/", nil)
+	req.Header.Set(fiber.HeaderAcceptLanguage, "en")
+	if token != "" {
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+	}
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	var ret response.Response
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&ret))
+	return ret
+}
+
+func TestAuthenticateHS(t *testing.T) {
+	secret := []byte("secret")
+	app := newAuthApp(AuthConfig{Key: secret, Issuer: "toolkit", Audience: "api", Leeway: time.Minute})
+	claims := jwt.MapClaims{
+		"sub":   "u1",
+		"iss":   "toolkit",
+		"aud":   "api",
+		"roles": []string{"admin"},
+		"exp":   time.Now().Add(-30 * time.Second).Unix(),
+	}
+	ret := doAuth(t, app, sign(t, jwt.SigningMethodHS256, secret, "", claims))
+	assert.Equal(t, 0, ret.Code)
+	assert.Equal(t, "u1", ret.Data.(map[string]interface{})["user"])
+	assert.Equal(t, []interface{}{"admin"}, ret.Data.(map[string]interface{})["roles"])
+
+	claims["exp"] = time.Now().Add(-2 * time.Minute).Unix()
+	ret = doAuth(t, app, sign(t, jwt.SigningMethodHS256, secret, "", claims))
+	assert.Equal(t, xerror.IllegalAccessToken, ret.Code)
+	assert.Equal(t, "Illegal token", ret.Message)
+
+	claims["exp"] = time.Now().Add(time.Minute).Unix()
+	claims["iss"] = "other"
+	ret = doAuth(t, app, sign(t, jwt.SigningMethodHS256, secret, "", claims))
+	assert.Equal(t, xerror.IllegalAccessToken, ret.Code)
+
+	ret = doAuth(t, app, "")
+	assert.Equal(t, xerror.IllegalAccessToken, ret.Code)
+}
+
+func TestAuthenticateJWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	set, _ := json.Marshal(map[string]interface{}{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa", "use": "sig", "n": b64(rsaKey.N), "e": b64(big.NewInt(int64(rsaKey.E)))},
+		{"kty": "EC", "kid": "ec", "crv": "P-256", "x": b64(ecKey.X), "y": b64(ecKey.Y)},
+	}})
+
+	filename := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(filename, set, 0o600))
+	fromFile, err := LoadJWKSFile(filename)
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(set)
+	}))
+	defer srv.Close()
+	fromURL, err := FetchJWKS(context.Background(), srv.URL)
+	require.NoError(t, err)
+
+	claims := jwt.MapClaims{"sub": "u2", "exp": time.Now().Add(time.Minute).Unix()}
+	for _, keySet := range []*JWKS{fromFile, fromURL} {
+		app := newAuthApp(AuthConfig{KeySet: keySet})
+		ret := doAuth(t, app, sign(t, jwt.SigningMethodRS256, rsaKey, "rsa", claims))
+		assert.Equal(t, 0, ret.Code)
+		ret = doAuth(t, app, sign(t, jwt.SigningMethodES256, ecKey, "ec", claims))
+		assert.Equal(t, 0, ret.Code)
+		assert.Equal(t, "u2", ret.Data.(map[string]interface{})["user"])
+
+		ret = doAuth(t, app, sign(t, jwt.SigningMethodES256, ecKey, "unknown", claims))
+		assert.Equal(t, xerror.IllegalCertificate, ret.Code)
+		ret = doAuth(t, app, sign(t, jwt.SigningMethodES256, ecKey, "rsa", claims))
+		assert.Equal(t, xerror.IllegalAccessToken, ret.Code)
+	}
+}
+
+func TestJWKSRotation(t *testing.T) {
+	oldKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	newKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	jwk := func(kid string, key *ecdsa.PrivateKey) map[string]string {
+		return map[string]string{"kty": "EC", "kid": kid, "crv": "P-256", "x": b64(key.X), "y": b64(key.Y)}
+	}
+	var (
+		mu      sync.Mutex
+		fetches int
+		keys    = []map[string]string{jwk("old", oldKey)}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		fetches++
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	}))
+	defer srv.Close()
+	set, err := FetchJWKS(context.Background(), srv.URL, JWKSConfig{MinRefreshInterval: time.Millisecond})
+	require.NoError(t, err)
+	app := newAuthApp(AuthConfig{KeySet: set})
+	claims := jwt.MapClaims{"sub": "u3", "exp": time.Now().Add(time.Minute).Unix()}
+	assert.Equal(t, 0, doAuth(t, app, sign(t, jwt.SigningMethodES256, oldKey, "old", claims)).Code)
+
+	// 轮换后未知 kid 触发重新拉取
+	mu.Lock()
+	keys = []map[string]string{jwk("new", newKey)}
+	mu.Unlock()
+	time.Sleep(2 * time.Millisecond)
+	assert.Equal(t, 0, doAuth(t, app, sign(t, jwt.SigningMethodES256, newKey, "new", claims)).Code)
+	assert.Equal(t, xerror.IllegalCertificate, doAuth(t, app, sign(t, jwt.SigningMethodES256, oldKey, "old", claims)).Code)
+
+	// MinRefreshInterval 内不重复拉取
+	set, err = FetchJWKS(context.Background(), srv.URL)
+	require.NoError(t, err)
+	mu.Lock()
+	before := fetches
+	mu.Unlock()
+	_, err = set.Key("unknown")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	mu.Lock()
+	assert.Equal(t, before, fetches)
+	mu.Unlock()
+
+	_, err = ParseJWKS([]byte(`{"keys": [{"kty": "oct", "kid": "hs", "k": "c2VjcmV0"}]}`))
+	assert.Error(t, err)
+}
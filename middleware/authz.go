@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	fiber "github.com/gofiber/fiber/v2"
+	"github.com/olongfen/toolkit/db_data"
+	"github.com/olongfen/toolkit/multi/xerror"
+	"github.com/olongfen/toolkit/scontext"
+)
+
+// Policy decide whether principal can access the route
+type Policy func(c *fiber.Ctx, p scontext.Principal) (bool, error)
+
+// Requirement route authorization requirement
+type Requirement struct {
+	// Roles principal must have any of roles
+	Roles []string
+	// Scopes principal must have all of scopes
+	Scopes []string
+	// Policies principal must pass all of policies
+	Policies []Policy
+}
+
+// Authorize check principal set by Authenticate against requirement,
+// denials return a localized xerror.Forbidden which is answered with 403
+func Authorize(req Requirement) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		p, ok := scontext.GetPrincipal(ctx)
+		if !ok {
+			return xerror.NewErrorContext(ctx, xerror.IllegalAccessToken)
+		}
+		if len(req.Roles) > 0 {
+			var has bool
+			for _, role := range req.Roles {
+				if p.HasRole(role) {
+					has = true
+					break
+				}
+			}
+			if !has {
+				return xerror.NewErrorContext(ctx, xerror.Forbidden)
+			}
+		}
+		for _, scope := range req.Scopes {
+			if !p.HasScope(scope) {
+				return xerror.NewErrorContext(ctx, xerror.Forbidden)
+			}
+		}
+		for _, policy := range req.Policies {
+			allowed, err := policy(c, p)
+			if err != nil {
+				return err
+			}
+			if !allowed {
+				return xerror.NewErrorContext(ctx, xerror.Forbidden)
+			}
+		}
+		return c.Next()
+	}
+}
+
+// RequireRoles principal must have any of roles
+func RequireRoles(roles ...string) fiber.Handler {
+	return Authorize(Requirement{Roles: roles})
+}
+
+// RequireScopes principal must have all of scopes
+func RequireScopes(scopes ...string) fiber.Handler {
+	return Authorize(Requirement{Scopes: scopes})
+}
+
+// RequirePolicy principal must pass all of policies
+func RequirePolicy(policies ...Policy) fiber.Handler {
+	return Authorize(Requirement{Policies: policies})
+}
+
+// RolePermission rbac table, empty tenant grants the permission in every tenant
+type RolePermission struct {
+	ID         uint   `gorm:"primarykey;autoIncrement"`
+	Tenant     string `gorm:"size:64;uniqueIndex:idx_role_permission;comment:租户"`
+	Role       string `gorm:"size:64;uniqueIndex:idx_role_permission;comment:角色"`
+	Permission string `gorm:"size:128;uniqueIndex:idx_role_permission;comment:权限"`
+}
+
+// RBAC policy granting access when any role of principal has permission in its tenant
+func RBAC(data db_data.DBData, permission string) Policy {
+	return func(c *fiber.Ctx, p scontext.Principal) (bool, error) {
+		if len(p.Roles) == 0 {
+			return false, nil
+		}
+		var (
+			count int64
+		)
+		err := data.DB(c.UserContext()).Model(&RolePermission{}).
+			Where("role IN ? AND permission = ? AND tenant IN ?", p.Roles, permission, []string{"", p.Tenant}).
+			Count(&count).Error
+		if err != nil {
+			return false, err
+		}
+		return count > 0, nil
+	}
+}
@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"encoding/json"
+	"github.com/glebarez/sqlite"
+	fiber "github.com/gofiber/fiber/v2"
+	"github.com/olongfen/toolkit/db_data"
+	"github.com/olongfen/toolkit/multi/xerror"
+	"github.com/olongfen/toolkit/response"
+	"github.com/olongfen/toolkit/scontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAuthorize(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: response.ErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		if c.Get("X-Anonymous") != "" {
+			return c.Next()
+		}
+		c.SetUserContext(scontext.SetPrincipal(c.UserContext(), scontext.Principal{
+			UserUuid: "u1",
+			Tenant:   "t1",
+			Roles:    []string{"member"},
+			Scopes:   []string{"project:read"},
+		}))
+		return c.Next()
+	})
+	ok := func(c *fiber.Ctx) error {
+		return response.NewResponse().Success(c, nil)
+	}
+	sameTenant := func(c *fiber.Ctx, p scontext.Principal) (bool, error) {
+		return c.Params("tenant") == p.Tenant, nil
+	}
+	app.Get("/read", RequireScopes("project:read"), ok)
+	app.Get("/write", RequireScopes("project:read", "project:write"), ok)
+	app.Delete("/admin", RequireRoles("admin", "owner"), ok)
+	app.Get("/member", RequireRoles("admin", "member"), ok)
+	app.Get("/tenants/:tenant", RequirePolicy(sameTenant), ok)
+
+	cases := []struct {
+		method, target string
+		anonymous      bool
+		status, code   int
+	}{
+		{fiber.MethodGet, "/read", false, fiber.StatusOK, 0},
+		{fiber.MethodGet, "/write", false, fiber.StatusForbidden, xerror.Forbidden},
+		{fiber.MethodDelete, "/admin", false, fiber.StatusForbidden, xerror.Forbidden},
+		{fiber.MethodGet, "/member", false, fiber.StatusOK, 0},
+		{fiber.MethodGet, "/tenants/t1", false, fiber.StatusOK, 0},
+		{fiber.MethodGet, "/tenants/t2", false, fiber.StatusForbidden, xerror.Forbidden},
+		{fiber.MethodGet, "/read", true, fiber.StatusUnauthorized, xerror.IllegalAccessToken},
+	}
+	for _, v := range cases {
+		req := httptest.NewRequest(v.method, v.target, nil)
+		if v.anonymous {
+			req.Header.Set("X-Anonymous", "1")
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		var ret response.Response
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&ret))
+		assert.Equal(t, v.status, resp.StatusCode, v.target)
+		assert.Equal(t, v.code, ret.Code, v.target)
+	}
+}
+
+func TestRBAC(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "rbac.db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&RolePermission{}))
+	require.NoError(t, db.Create([]RolePermission{
+		{Tenant: "t1", Role: "editor", Permission: "article:write"},
+		{Role: "admin", Permission: "article:write"},
+	}).Error)
+	data, cleanup := db_data.NewData(db, zap.NewNop())
+	defer cleanup()
+
+	app := fiber.New(fiber.Config{ErrorHandler: response.ErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		if tenant := c.Get("X-Tenant"); tenant != "" {
+			c.SetUserContext(scontext.SetPrincipal(c.UserContext(), scontext.Principal{
+				UserUuid: "u1",
+				Tenant:   tenant,
+				Roles:    strings.Split(c.Get("X-Roles"), ","),
+			}))
+		}
+		return c.Next()
+	})
+	app.Post("/articles", RequirePolicy(RBAC(data, "article:write")), func(c *fiber.Ctx) error {
+		return response.NewResponse().Success(c, nil)
+	})
+
+	cases := []struct {
+		tenant, roles string
+		status, code  int
+	}{
+		{"t1", "editor", fiber.StatusOK, 0},
+		{"t2", "viewer,admin", fiber.StatusOK, 0},
+		{"t2", "editor", fiber.StatusForbidden, xerror.Forbidden},
+		{"t1", "", fiber.StatusForbidden, xerror.Forbidden},
+		{"", "", fiber.StatusUnauthorized, xerror.IllegalAccessToken},
+	}
+	for _, v := range cases {
+		req := httptest.NewRequest(fiber.MethodPost, "/articles", nil)
+		req.Header.Set("X-Tenant", v.tenant)
+		req.Header.Set("X-Roles", v.roles)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		var ret response.Response
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&ret))
+		assert.Equal(t, v.status, resp.StatusCode, v)
+		assert.Equal(t, v.code, ret.Code, v)
+	}
+}
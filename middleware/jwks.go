@@ -0,0 +1,237 @@
+package middleware
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrKeyNotFound no key matches token kid
+var ErrKeyNotFound = errors.New("jwks: key not found")
+
+// jsonWebKey json web key, only the fields used for verification
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// JWKS json web key set, sets fetched by FetchJWKS are refreshed periodically and on unknown kid
+type JWKS struct {
+	mu        sync.RWMutex
+	keys      map[string]interface{}
+	url       string
+	conf      JWKSConfig
+	fetchedAt time.Time
+	// refreshMu 保证同一时间只有一个请求在拉取
+	refreshMu sync.Mutex
+}
+
+// JWKSConfig remote key set config
+type JWKSConfig struct {
+	// RefreshInterval refetch the set when it is older, default 1h
+	RefreshInterval time.Duration
+	// MinRefreshInterval min interval between fetches, limits refetching on unknown kid, default 1m
+	MinRefreshInterval time.Duration
+	// Timeout timeout of a refetch, default 10s
+	Timeout time.Duration
+	// Client http client, default http.DefaultClient
+	Client *http.Client
+}
+
+// ParseJWKS parse json web key set, symmetric (oct) keys are rejected as a key set only publishes public keys
+func ParseJWKS(b []byte) (*JWKS, error) {
+	var (
+		set struct {
+			Keys []jsonWebKey `json:"keys"`
+		}
+	)
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, err
+	}
+	ret := &JWKS{keys: map[string]interface{}{}}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("jwks: kid %q: %w", k.Kid, err)
+		}
+		ret.keys[k.Kid] = key
+	}
+	return ret, nil
+}
+
+// LoadJWKSFile load json web key set from file
+func LoadJWKSFile(filename string) (*JWKS, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseJWKS(b)
+}
+
+// FetchJWKS fetch json web key set from url, Key refetches it when it is older than RefreshInterval
+// or kid is unknown, so rotated keys are picked up without restart
+func FetchJWKS(ctx context.Context, url string, config ...JWKSConfig) (*JWKS, error) {
+	var (
+		conf JWKSConfig
+	)
+	if len(config) > 0 {
+		conf = config[0]
+	}
+	if conf.RefreshInterval <= 0 {
+		conf.RefreshInterval = time.Hour
+	}
+	if conf.MinRefreshInterval <= 0 {
+		conf.MinRefreshInterval = time.Minute
+	}
+	if conf.Timeout <= 0 {
+		conf.Timeout = 10 * time.Second
+	}
+	if conf.Client == nil {
+		conf.Client = http.DefaultClient
+	}
+	keys, err := fetchKeys(ctx, conf.Client, url)
+	if err != nil {
+		return nil, err
+	}
+	return &JWKS{keys: keys, url: url, conf: conf, fetchedAt: time.Now()}, nil
+}
+
+func fetchKeys(ctx context.Context, client *http.Client, url string) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwks: fetch %s: status %d", url, resp.StatusCode)
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	set, err := ParseJWKS(b)
+	if err != nil {
+		return nil, err
+	}
+	return set.keys, nil
+}
+
+// Key get key by kid, the only key is returned when kid is empty
+func (s *JWKS) Key(kid string) (interface{}, error) {
+	s.mu.RLock()
+	key, err := s.lookup(kid)
+	stale := s.url != "" && time.Since(s.fetchedAt) >= s.conf.RefreshInterval
+	s.mu.RUnlock()
+	if s.url == "" || err == nil && !stale {
+		return key, err
+	}
+	s.refresh()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lookup(kid)
+}
+
+// refresh refetch the set at most once per MinRefreshInterval, the current keys are kept when fetching fails
+func (s *JWKS) refresh() {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+	s.mu.RLock()
+	recent := time.Since(s.fetchedAt) < s.conf.MinRefreshInterval
+	s.mu.RUnlock()
+	if recent {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.conf.Timeout)
+	defer cancel()
+	keys, err := fetchKeys(ctx, s.conf.Client, s.url)
+	s.mu.Lock()
+	s.fetchedAt = time.Now()
+	if err == nil {
+		s.keys = keys
+	}
+	s.mu.Unlock()
+}
+
+func (s *JWKS) lookup(kid string) (interface{}, error) {
+	if key, ok := s.keys[kid]; ok {
+		return key, nil
+	}
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, nil
+		}
+	}
+	return nil, ErrKeyNotFound
+}
+
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "oct":
+		return nil, errors.New("symmetric key not allowed")
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	fiber "github.com/gofiber/fiber/v2"
+	"github.com/olongfen/toolkit/consts"
+	"github.com/olongfen/toolkit/scontext"
+	"golang.org/x/text/language"
+	"strings"
+)
+
+// LanguageConfig language middleware config
+type LanguageConfig struct {
+	// Next skip middleware when return true
+	Next func(c *fiber.Ctx) bool
+	// Supported supported languages, the first one is default, default consts.SupportedLanguages
+	Supported []string
+	// QueryKey query key, default "lang"
+	QueryKey string
+	// CookieName cookie name, default "lang"
+	CookieName string
+}
+
+// Language negotiate request language by query, cookie and Accept-Language in turn,
+// the matched language is stored by scontext.SetLanguage on ctx.UserContext()
+func Language(config ...LanguageConfig) fiber.Handler {
+	var (
+		conf LanguageConfig
+	)
+	if len(config) > 0 {
+		conf = config[0]
+	}
+	if len(conf.Supported) == 0 {
+		conf.Supported = consts.SupportedLanguages
+	}
+	if conf.QueryKey == "" {
+		conf.QueryKey = "lang"
+	}
+	if conf.CookieName == "" {
+		conf.CookieName = "lang"
+	}
+	supported := make([]string, 0, len(conf.Supported))
+	tags := make([]language.Tag, 0, len(conf.Supported))
+	for _, v := range conf.Supported {
+		supported = append(supported, strings.ToLower(v))
+		tags = append(tags, language.Make(v))
+	}
+	matcher := language.NewMatcher(tags)
+
+	match := func(candidates ...language.Tag) (string, bool) {
+		if len(candidates) == 0 {
+			return "", false
+		}
+		_, index, confidence := matcher.Match(candidates...)
+		if confidence == language.No {
+			return "", false
+		}
+		return supported[index], true
+	}
+
+	return func(c *fiber.Ctx) error {
+		if conf.Next != nil && conf.Next(c) {
+			return c.Next()
+		}
+		lang, ok := "", false
+		for _, v := range []string{c.Query(conf.QueryKey), c.Cookies(conf.CookieName)} {
+			if v == "" {
+				continue
+			}
+			if tag, err := language.Parse(strings.ReplaceAll(v, "_", "-")); err == nil {
+				if lang, ok = match(tag); ok {
+					break
+				}
+			}
+		}
+		if !ok {
+			tags, _, _ := language.ParseAcceptLanguage(c.Get(fiber.HeaderAcceptLanguage))
+			lang, ok = match(tags...)
+		}
+		if !ok {
+			lang = supported[0]
+		}
+		c.Vary(fiber.HeaderAcceptLanguage)
+		c.Set(fiber.HeaderContentLanguage, lang)
+		c.SetUserContext(scontext.SetLanguage(c.UserContext(), lang))
+		return c.Next()
+	}
+}
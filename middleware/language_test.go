@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	fiber "github.com/gofiber/fiber/v2"
+	"github.com/olongfen/toolkit/consts"
+	"github.com/olongfen/toolkit/scontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLanguage(t *testing.T) {
+	app := fiber.New()
+	app.Use(Language())
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString(scontext.GetLanguage(c.UserContext()))
+	})
+
+	cases := []struct {
+		target, header, cookie, want string
+	}{
+		{"/", "", "", consts.SimplifiedChinese},
+		{"/", "zh-Hant-HK", "", consts.TraditionalChinese},
+		{"/", "ja, en-US;q=0.8, zh;q=0.5", "", consts.English},
+		{"/", "fr", "", consts.SimplifiedChinese},
+		{"/?lang=en", "zh-TW", "", consts.English},
+		{"/?lang=xx", "zh-TW", "", consts.TraditionalChinese},
+		{"/", "zh-TW", "en", consts.English},
+		{"/?lang=zh_TW", "", "en", consts.TraditionalChinese},
+	}
+	for _, v := range cases {
+		req := httptest.NewRequest(fiber.MethodGet, v.target, nil)
+		if v.header != "" {
+			req.Header.Set(fiber.HeaderAcceptLanguage, v.header)
+		}
+		if v.cookie != "" {
+			req.Header.Set(fiber.HeaderCookie, "lang="+v.cookie)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		b, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, v.want, string(b), v)
+		assert.Equal(t, v.want, resp.Header.Get(fiber.HeaderContentLanguage))
+	}
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	fiber "github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/olongfen/toolkit/consts"
+	"github.com/olongfen/toolkit/scontext"
+)
+
+// RequestIDConfig request id middleware config
+type RequestIDConfig struct {
+	// Next skip middleware when return true
+	Next func(c *fiber.Ctx) bool
+	// Header request id header, default consts.HeaderRequestID
+	Header string
+	// Generator generate request id when request has none, default uuid
+	Generator func() string
+}
+
+// RequestID honour incoming request id or generate one, the id is stored by
+// scontext.SetRequestID and echoed with the active trace id in response headers
+func RequestID(config ...RequestIDConfig) fiber.Handler {
+	var (
+		conf RequestIDConfig
+	)
+	if len(config) > 0 {
+		conf = config[0]
+	}
+	if conf.Header == "" {
+		conf.Header = consts.HeaderRequestID
+	}
+	if conf.Generator == nil {
+		conf.Generator = uuid.NewString
+	}
+	return func(c *fiber.Ctx) error {
+		if conf.Next != nil && conf.Next(c) {
+			return c.Next()
+		}
+		rid := c.Get(conf.Header)
+		if !validRequestID(rid) {
+			rid = conf.Generator()
+		}
+		ctx := scontext.SetRequestID(c.UserContext(), rid)
+		if p, ok := scontext.GetPrincipal(ctx); ok {
+			p.RequestID = rid
+			ctx = scontext.SetPrincipal(ctx, p)
+		}
+		c.SetUserContext(ctx)
+		c.Set(conf.Header, rid)
+		if tid := scontext.GetTraceID(ctx); tid != "" {
+			c.Set(consts.HeaderTraceID, tid)
+		}
+		return c.Next()
+	}
+}
+
+// validRequestID incoming id must be short printable ascii to be safe in logs and headers
+func validRequestID(rid string) bool {
+	if rid == "" || len(rid) > 128 {
+		return false
+	}
+	for i := 0; i < len(rid); i++ {
+		if rid[i] < 0x21 || rid[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
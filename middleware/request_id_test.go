@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"encoding/json"
+	fiber "github.com/gofiber/fiber/v2"
+	"github.com/olongfen/toolkit/consts"
+	"github.com/olongfen/toolkit/response"
+	"github.com/olongfen/toolkit/scontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	app := fiber.New(fiber.Config{ErrorHandler: response.ErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		sc := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID})
+		c.SetUserContext(trace.ContextWithSpanContext(c.UserContext(), sc))
+		return c.Next()
+	}, RequestID())
+	app.Get("/ok", func(c *fiber.Ctx) error {
+		return response.NewResponse().Success(c, scontext.GetRequestID(c.UserContext()))
+	})
+	app.Get("/fail", func(c *fiber.Ctx) error {
+		return fiber.ErrBadRequest
+	})
+
+	for _, target := range []string{"/ok", "/fail"} {
+		req := httptest.NewRequest(fiber.MethodGet, target, nil)
+		req.Header.Set(consts.HeaderRequestID, "req-1")
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, "req-1", resp.Header.Get(consts.HeaderRequestID))
+		assert.Equal(t, traceID.String(), resp.Header.Get(consts.HeaderTraceID))
+		var ret response.Response
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&ret))
+		assert.Equal(t, "req-1", ret.RequestID)
+		assert.Equal(t, traceID.String(), ret.TraceID)
+	}
+
+	req := httptest.NewRequest(fiber.MethodGet, "/ok", nil)
+	req.Header.Set(consts.HeaderRequestID, "bad id\n")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	rid := resp.Header.Get(consts.HeaderRequestID)
+	assert.Len(t, rid, 36)
+	var ret response.Response
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&ret))
+	assert.Equal(t, rid, ret.Data)
+}
@@ -0,0 +1,28 @@
+package xerror_test
+
+import (
+	"github.com/olongfen/toolkit/consts"
+	"github.com/olongfen/toolkit/multi/xerror"
+	"github.com/olongfen/toolkit/multi/xerror/xerrortest"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCatalogueComplete(t *testing.T) {
+	xerrortest.AssertComplete(t, xerror.DefaultErrorMul)
+}
+
+func TestFallback(t *testing.T) {
+	const code = 40097
+	defer xerror.DefaultErrorMul.DeleteKey(code)
+	xerror.DefaultErrorMul.Set(code, "en", "english only")
+	assert.Equal(t, "english only", xerror.DefaultErrorMul.Get(code, consts.TraditionalChinese))
+	xerror.DefaultErrorMul.Set(code, "zh_CN", "简体")
+	assert.Equal(t, "简体", xerror.DefaultErrorMul.Get(code, consts.TraditionalChinese))
+	assert.Equal(t, "简体", xerror.DefaultErrorMul.Get(code, "zh-Hans"))
+	assert.Equal(t, "简体", xerror.DefaultErrorMul.Get(code, "ja"))
+	xerror.DefaultErrorMul.Set(code, "zh-Hant", "繁體")
+	assert.Equal(t, "繁體", xerror.DefaultErrorMul.Get(code, "zh-HK"))
+	assert.Equal(t, "english only", xerror.DefaultErrorMul.Get(code, "en-GB"))
+	assert.Equal(t, "40096", xerror.DefaultErrorMul.Get(40096, consts.English))
+}
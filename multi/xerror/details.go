@@ -0,0 +1,259 @@
+package xerror
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 错误详情, 对应 google.rpc error details, json 形式为 {"@type": TypeURL, ...字段}
+const (
+	typePrefix = "type.googleapis.com/google.rpc."
+
+	TypeBadRequest          = typePrefix + "BadRequest"
+	TypePreconditionFailure = typePrefix + "PreconditionFailure"
+	TypeQuotaFailure        = typePrefix + "QuotaFailure"
+	TypeRetryInfo           = typePrefix + "RetryInfo"
+	TypeHelp                = typePrefix + "Help"
+	TypeLocalizedMessage    = typePrefix + "LocalizedMessage"
+	TypeResourceInfo        = typePrefix + "ResourceInfo"
+)
+
+// Detail structured error detail
+type Detail interface {
+	// TypeURL "@type" of the json form, e.g. TypeBadRequest
+	TypeURL() string
+}
+
+// FieldViolation invalid field of a request
+type FieldViolation struct {
+	// Field path of the field, e.g. "user.email"
+	Field string `json:"field"`
+	// Rule failed validation rule, e.g. "required" or "max"
+	Rule string `json:"rule,omitempty"`
+	// Params params of the rule, e.g. {"max": 10}
+	Params Args `json:"params,omitempty"`
+	// Description localized description
+	Description string `json:"description,omitempty"`
+}
+
+// BadRequest field violations of a request
+type BadRequest struct {
+	FieldViolations []FieldViolation `json:"field_violations"`
+}
+
+// PreconditionViolation failed precondition
+type PreconditionViolation struct {
+	// Type type of the precondition, e.g. "TOS" or "ETAG"
+	Type        string `json:"type"`
+	Subject     string `json:"subject"`
+	Description string `json:"description,omitempty"`
+}
+
+// PreconditionFailure failed preconditions
+type PreconditionFailure struct {
+	Violations []PreconditionViolation `json:"violations"`
+}
+
+// QuotaViolation exceeded quota
+type QuotaViolation struct {
+	// Subject subject of the quota, e.g. "user:1001" or "project:toolkit"
+	Subject     string `json:"subject"`
+	Description string `json:"description,omitempty"`
+}
+
+// QuotaFailure exceeded quotas
+type QuotaFailure struct {
+	Violations []QuotaViolation `json:"violations"`
+}
+
+// RetryInfo retry after RetryDelay, rendered as protobuf duration, e.g. "1.5s"
+type RetryInfo struct {
+	RetryDelay time.Duration `json:"-"`
+}
+
+// HelpLink help link
+type HelpLink struct {
+	Description string `json:"description,omitempty"`
+	URL         string `json:"url"`
+}
+
+// Help links to documentation
+type Help struct {
+	Links []HelpLink `json:"links"`
+}
+
+// LocalizedMessage message in locale
+type LocalizedMessage struct {
+	Locale  string `json:"locale"`
+	Message string `json:"message"`
+}
+
+// ResourceInfo resource being accessed
+type ResourceInfo struct {
+	ResourceType string `json:"resource_type"`
+	ResourceName string `json:"resource_name"`
+	Owner        string `json:"owner,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+func (BadRequest) TypeURL() string          { return TypeBadRequest }
+func (PreconditionFailure) TypeURL() string { return TypePreconditionFailure }
+func (QuotaFailure) TypeURL() string        { return TypeQuotaFailure }
+func (RetryInfo) TypeURL() string           { return TypeRetryInfo }
+func (Help) TypeURL() string                { return TypeHelp }
+func (LocalizedMessage) TypeURL() string    { return TypeLocalizedMessage }
+func (ResourceInfo) TypeURL() string        { return TypeResourceInfo }
+
+type retryInfoJSON struct {
+	RetryDelay string `json:"retry_delay"`
+}
+
+// MarshalJSON {"retry_delay": "1.5s"}
+func (r RetryInfo) MarshalJSON() ([]byte, error) {
+	return json.Marshal(retryInfoJSON{RetryDelay: strconv.FormatFloat(r.RetryDelay.Seconds(), 'f', -1, 64) + "s"})
+}
+
+// UnmarshalJSON parse {"retry_delay": "1.5s"}
+func (r *RetryInfo) UnmarshalJSON(b []byte) error {
+	var v retryInfoJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	seconds, err := strconv.ParseFloat(strings.TrimSuffix(v.RetryDelay, "s"), 64)
+	if err != nil {
+		return fmt.Errorf("xerror: invalid retry_delay %q", v.RetryDelay)
+	}
+	r.RetryDelay = time.Duration(seconds * float64(time.Second))
+	return nil
+}
+
+// detailTypes new detail of type url, used to decode Details
+var detailTypes = map[string]func() Detail{
+	TypeBadRequest:          func() Detail { return &BadRequest{} },
+	TypePreconditionFailure: func() Detail { return &PreconditionFailure{} },
+	TypeQuotaFailure:        func() Detail { return &QuotaFailure{} },
+	TypeRetryInfo:           func() Detail { return &RetryInfo{} },
+	TypeHelp:                func() Detail { return &Help{} },
+	TypeLocalizedMessage:    func() Detail { return &LocalizedMessage{} },
+	TypeResourceInfo:        func() Detail { return &ResourceInfo{} },
+}
+
+// Details details of an error, each rendered as its json fields plus "@type"
+type Details []Detail
+
+// MarshalJSON [{"@type": "type.googleapis.com/google.rpc.BadRequest", "field_violations": [...]}, ...]
+func (d Details) MarshalJSON() ([]byte, error) {
+	ret := make([]json.RawMessage, 0, len(d))
+	for _, v := range d {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		var fields map[string]json.RawMessage
+		if err = json.Unmarshal(b, &fields); err != nil {
+			return nil, fmt.Errorf("xerror: detail %s is not a json object", v.TypeURL())
+		}
+		if fields == nil {
+			fields = map[string]json.RawMessage{}
+		}
+		fields["@type"], _ = json.Marshal(v.TypeURL())
+		if b, err = json.Marshal(fields); err != nil {
+			return nil, err
+		}
+		ret = append(ret, b)
+	}
+	return json.Marshal(ret)
+}
+
+// UnmarshalJSON decode details of known types, unknown types are skipped
+func (d *Details) UnmarshalJSON(b []byte) error {
+	var raws []json.RawMessage
+	if err := json.Unmarshal(b, &raws); err != nil {
+		return err
+	}
+	ret := make(Details, 0, len(raws))
+	for _, raw := range raws {
+		var t struct {
+			Type string `json:"@type"`
+		}
+		if err := json.Unmarshal(raw, &t); err != nil {
+			return err
+		}
+		fn, ok := detailTypes[t.Type]
+		if !ok {
+			continue
+		}
+		v := fn()
+		if err := json.Unmarshal(raw, v); err != nil {
+			return fmt.Errorf("xerror: detail %s: %w", t.Type, err)
+		}
+		ret = append(ret, deref(v))
+	}
+	*d = ret
+	return nil
+}
+
+// deref value of a decoded detail, so details compare equal to the ones attached
+func deref(d Detail) Detail {
+	switch v := d.(type) {
+	case *BadRequest:
+		return *v
+	case *PreconditionFailure:
+		return *v
+	case *QuotaFailure:
+		return *v
+	case *RetryInfo:
+		return *v
+	case *Help:
+		return *v
+	case *LocalizedMessage:
+		return *v
+	case *ResourceInfo:
+		return *v
+	}
+	return d
+}
+
+// BadRequest field violations of v sorted by field, messages are the descriptions
+func (v ValidateError) BadRequest() BadRequest {
+	ret := BadRequest{FieldViolations: make([]FieldViolation, 0, len(v))}
+	for field, msg := range v {
+		ret.FieldViolations = append(ret.FieldViolations, FieldViolation{Field: field, Description: msg})
+	}
+	sort.Slice(ret.FieldViolations, func(i, j int) bool {
+		return ret.FieldViolations[i].Field < ret.FieldViolations[j].Field
+	})
+	return ret
+}
+
+// BadRequest v sorted by field
+func (v FieldViolations) BadRequest() BadRequest {
+	ret := BadRequest{FieldViolations: append(make([]FieldViolation, 0, len(v)), v...)}
+	sort.SliceStable(ret.FieldViolations, func(i, j int) bool {
+		return ret.FieldViolations[i].Field < ret.FieldViolations[j].Field
+	})
+	return ret
+}
+
+// Fields fields of c sorted
+func (c DBErrorResponse) Fields() []string {
+	ret := make([]string, 0, len(c))
+	for k := range c {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// BadRequest fields of c sorted, the localized messages in lan are the descriptions
+func (c DBErrorResponse) BadRequest(lan string) BadRequest {
+	ret := BadRequest{FieldViolations: make([]FieldViolation, 0, len(c))}
+	for _, field := range c.Fields() {
+		ret.FieldViolations = append(ret.FieldViolations, FieldViolation{Field: field, Description: c[field].Localize(lan)})
+	}
+	return ret
+}
@@ -0,0 +1,59 @@
+package xerror
+
+import (
+	"encoding/json"
+	"github.com/olongfen/toolkit/consts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestDetails(t *testing.T) {
+	err := NewError(IllegalParameter, consts.English).WithDetails(
+		BadRequest{FieldViolations: []FieldViolation{{Field: "tags", Rule: "max", Params: Args{"max": 10}, Description: "at most 10 tags"}}},
+		RetryInfo{RetryDelay: 1500 * time.Millisecond},
+		Help{Links: []HelpLink{{Description: "tags", URL: "https://example.com/docs/tags"}}},
+		ResourceInfo{ResourceType: "article", ResourceName: "articles/1"},
+	)
+	b, e := json.Marshal(err.Details())
+	require.NoError(t, e)
+	assert.JSONEq(t, `[
+		{"@type": "type.googleapis.com/google.rpc.BadRequest", "field_violations": [{"field": "tags", "rule": "max", "params": {"max": 10}, "description": "at most 10 tags"}]},
+		{"@type": "type.googleapis.com/google.rpc.RetryInfo", "retry_delay": "1.5s"},
+		{"@type": "type.googleapis.com/google.rpc.Help", "links": [{"description": "tags", "url": "https://example.com/docs/tags"}]},
+		{"@type": "type.googleapis.com/google.rpc.ResourceInfo", "resource_type": "article", "resource_name": "articles/1"}
+	]`, string(b))
+
+	var details Details
+	require.NoError(t, json.Unmarshal(append(b[:len(b)-1], []byte(`,{"@type": "type.googleapis.com/unknown"}]`)...), &details))
+	require.Len(t, details, 4)
+	assert.Equal(t, RetryInfo{RetryDelay: 1500 * time.Millisecond}, details[1])
+	assert.Equal(t, err.Details()[3], details[3])
+
+	assert.Nil(t, NewError(IllegalParameter, consts.English).Details())
+	assert.Equal(t, BadRequest{FieldViolations: []FieldViolation{{Field: "email", Description: "invalid"}, {Field: "name", Description: "required"}}},
+		ValidateError{"name": "required", "email": "invalid"}.BadRequest())
+
+	// WithDetails 返回副本, 不修改 err 的详情
+	more := err.WithDetails(RetryInfo{RetryDelay: time.Second})
+	assert.Len(t, err.Details(), 4)
+	assert.Len(t, more.Details(), 5)
+
+	violations := FieldViolations{
+		{Field: "name", Rule: "required", Description: "required"},
+		{Field: "age", Rule: "max", Params: Args{"max": 10}, Description: "too old"},
+	}
+	assert.Equal(t, BadRequest{FieldViolations: []FieldViolation{violations[1], violations[0]}}, violations.BadRequest())
+	assert.Equal(t, "name", violations[0].Field)
+	var validate ValidateError
+	require.ErrorAs(t, error(violations), &validate)
+	assert.Equal(t, ValidateError{"name": "required", "age": "too old"}, validate)
+
+	db := DBErrorResponse{"name": NewError(AlreadyExists, consts.English), "email": NewError(RecordNotFound, consts.English)}
+	assert.Equal(t, []string{"email", "name"}, db.Fields())
+	assert.Equal(t, BadRequest{FieldViolations: []FieldViolation{
+		{Field: "email", Description: "record not found"},
+		{Field: "name", Description: "already exists,duplicate creation is not allowed"},
+	}}, db.BadRequest(consts.English))
+}
@@ -1,9 +1,12 @@
 package xerror
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/olongfen/toolkit/scontext"
 	"github.com/pkg/errors"
+	"strconv"
 )
 
 type ValidateError map[string]string
@@ -13,27 +16,80 @@ func (v ValidateError) Error() string {
 	return fmt.Sprintf("%s", b)
 }
 
+// FieldViolations validation error keeping the failed rule and its params of every field, returned by tools.Validate,
+// errors.As finds the ValidateError of it
+type FieldViolations []FieldViolation
+
+func (v FieldViolations) Error() string {
+	return v.ValidateError().Error()
+}
+
+// ValidateError field -> description
+func (v FieldViolations) ValidateError() ValidateError {
+	ret := make(ValidateError, len(v))
+	for _, f := range v {
+		ret[f.Field] = f.Description
+	}
+	return ret
+}
+
+func (v FieldViolations) Unwrap() error {
+	return v.ValidateError()
+}
+
 var _ BizError = (*bizError)(nil)
 
 type BizError interface {
 	// i 为了避免被其他包实现
 	i()
 	Code() int
+	// With 方法均返回副本, 不修改原错误
 	// WithError 设置错误信息
 	WithError(err error) BizError
+	// WithArgs 设置消息模板参数
+	WithArgs(args Args) BizError
+	// WithLanguage 设置 Error 的渲染语言
+	WithLanguage(lang string) BizError
+	// Args 消息模板参数
+	Args() Args
+	// WithDetails 附加错误详情
+	WithDetails(details ...Detail) BizError
+	// Details 错误详情
+	Details() Details
+	// Language 创建时的语言, 决定 Error 的渲染语言
+	Language() string
+	// Localize 渲染时按 lang 翻译消息
+	Localize(lang string) string
 
 	Error() string
 
 	// StackError 获取带堆栈的错误信息
 	StackError() error
+	// Stack 创建时的调用栈, CaptureStack 关闭时为空
+	Stack() []Frame
+	// Fingerprint code 与栈顶函数的哈希, 用于错误聚合
+	Fingerprint() string
 }
 
+// bizError 只保存 code 与模板参数, 消息在渲染时翻译
 type bizError struct {
-	code    int
-	message string // 错误描述
-	stack   error  // 含有堆栈信息的错误
+	code     int
+	language string
+	args     Args
+	details  Details
+	mul      *ErrorMul
+	fallback string    // 目录中没有翻译时使用的消息
+	pcs      []uintptr // 创建时的调用栈
+	cause    error     // 原始错误
+	stack    error     // 含有堆栈信息的错误
 }
 
+// Args template data of a message, e.g. {"Field": "email"} for "field '{{.Field}}' already exists"
+type Args map[string]interface{}
+
+// PluralCount key of Args selecting the plural form of the message
+const PluralCount = "PluralCount"
+
 type DBErrorResponse map[string]BizError
 
 func (c DBErrorResponse) Error() string {
@@ -41,19 +97,90 @@ func (c DBErrorResponse) Error() string {
 	return string(b)
 }
 
+// NewError new error of code, Error renders the message in language
 func NewError(code int, language string, errMul ...*ErrorMul) BizError {
-	biz := &bizError{
-		message: "",
-		code:    code,
+	return newError(0, code, language, errMul...)
+}
+
+// New new error of DefaultErrorMul without language, Error renders the message in the default language,
+// use Localize to render it in another one
+func New(code int, args ...Args) BizError {
+	e := newError(0, code, "")
+	for _, a := range args {
+		e.mergeArgs(a)
+	}
+	return e
+}
+
+// NewErrorContext new error of DefaultErrorMul in the language of ctx, args render the message template
+func NewErrorContext(ctx context.Context, code int, args ...Args) BizError {
+	e := newError(0, code, scontext.GetLanguage(ctx))
+	for _, a := range args {
+		e.mergeArgs(a)
+	}
+	return e
+}
+
+// NewErrorContextSkip NewErrorContext leaving skip frames above the caller out of the stack,
+// the generated New<Name> constructors pass 1 so the stack starts at their caller
+func NewErrorContextSkip(ctx context.Context, skip int, code int, args ...Args) BizError {
+	e := newError(skip, code, scontext.GetLanguage(ctx))
+	for _, a := range args {
+		e.mergeArgs(a)
+	}
+	return e
+}
+
+// Restore restore error received from another service, message is used when the catalogue has no translation of code
+func Restore(code int, language string, message string, args Args, details ...Detail) BizError {
+	e := newError(0, code, language)
+	e.mergeArgs(args)
+	e.details = details
+	e.fallback = message
+	return e
+}
+
+// NewErrorf new error rendering the message template of code with args
+func NewErrorf(code int, language string, args Args, errMul ...*ErrorMul) BizError {
+	e := newError(0, code, language, errMul...)
+	e.mergeArgs(args)
+	return e
+}
+
+// newError must be called by the exported constructors directly, so the stack starts skip frames above their caller
+func newError(skip int, code int, language string, errMul ...*ErrorMul) *bizError {
+	e := &bizError{
+		code:     code,
+		language: language,
+		mul:      DefaultErrorMul,
+		pcs:      callers(2 + skip),
 	}
-	var (
-		defaultErrMul = DefaultErrorMul
-	)
 	if len(errMul) > 0 {
-		defaultErrMul = errMul[0]
+		e.mul = errMul[0]
+	}
+	return e
+}
+
+// clone copy of e, With methods never modify the receiver so shared errors are safe to use concurrently
+func (e *bizError) clone() *bizError {
+	c := *e
+	if e.args != nil {
+		c.args = e.Args()
+	}
+	c.details = e.Details()
+	return &c
+}
+
+func (e *bizError) mergeArgs(args Args) {
+	if len(args) == 0 {
+		return
+	}
+	if e.args == nil {
+		e.args = Args{}
+	}
+	for k, v := range args {
+		e.args[k] = v
 	}
-	biz.message = defaultErrMul.Get(code, language)
-	return biz
 }
 
 func (e *bizError) i() {}
@@ -63,18 +190,106 @@ func (e *bizError) Code() int {
 }
 
 func (e *bizError) Error() string {
-	return fmt.Sprintf(`%s`, e.message)
+	return e.Localize(e.language)
+}
+
+func (e *bizError) Language() string {
+	return e.language
+}
+
+// Localize render the message of code with args in lang
+func (e *bizError) Localize(lang string) string {
+	mul := e.mul
+	if mul == nil {
+		mul = DefaultErrorMul
+	}
+	var data interface{}
+	if len(e.args) > 0 {
+		data = map[string]interface{}(e.args)
+	}
+	if msg := mul.message(e.code, lang, data, e.args[PluralCount]); msg != "" {
+		return msg
+	}
+	if e.fallback != "" {
+		return e.fallback
+	}
+	return strconv.Itoa(e.code)
 }
 
 func (e *bizError) WithError(err error) BizError {
-	e.stack = errors.WithStack(err)
-	return e
+	c := e.clone()
+	c.cause = err
+	c.stack = errors.WithStack(err)
+	return c
+}
+
+func (e *bizError) WithArgs(args Args) BizError {
+	c := e.clone()
+	c.mergeArgs(args)
+	return c
+}
+
+func (e *bizError) WithDetails(details ...Detail) BizError {
+	c := e.clone()
+	c.details = append(c.details, details...)
+	return c
+}
+
+func (e *bizError) WithLanguage(lang string) BizError {
+	c := e.clone()
+	c.language = lang
+	return c
+}
+
+// Details copy of the attached details
+func (e *bizError) Details() Details {
+	if len(e.details) == 0 {
+		return nil
+	}
+	return append(Details(nil), e.details...)
+}
+
+// Args copy of the template data
+func (e *bizError) Args() Args {
+	if len(e.args) == 0 {
+		return nil
+	}
+	ret := make(Args, len(e.args))
+	for k, v := range e.args {
+		ret[k] = v
+	}
+	return ret
+}
+
+// Unwrap get the error set by WithError
+func (e *bizError) Unwrap() error {
+	return e.cause
+}
+
+// Is biz errors with the same code are equal
+func (e *bizError) Is(target error) bool {
+	t, ok := target.(BizError)
+	return ok && t.Code() == e.code
 }
 
 func (e *bizError) Message() string {
-	return e.message
+	return e.Error()
 }
 
 func (e *bizError) StackError() error {
 	return e.stack
 }
+
+// FromError find the first BizError in err chain
+func FromError(err error) (BizError, bool) {
+	var e BizError
+	if errors.As(err, &e) {
+		return e, true
+	}
+	return nil, false
+}
+
+// IsCode report whether err chain contains a BizError of code
+func IsCode(err error, code int) bool {
+	return errors.Is(err, &bizError{code: code})
+}
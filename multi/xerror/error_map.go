@@ -1,69 +1,219 @@
 package xerror
 
 import (
+	"embed"
+	"fmt"
 	"github.com/olongfen/toolkit/consts"
+	"github.com/olongfen/toolkit/i18n"
+	"strconv"
 	"sync"
+	"sync/atomic"
 )
 
 var (
-	// DefaultErrorMul default
-	DefaultErrorMul = &ErrorMul{
-		data: sync.Map{},
-	}
+	// DefaultErrorMul default, codes are declared in errors.yaml and messages are loaded from locales generated from it
+	DefaultErrorMul = NewErrorMul(i18n.NewBundle(consts.SimplifiedChinese))
+
+	//go:embed locales
+	locales embed.FS
 )
 
+//go:generate go run ../../cmd/xerrgen -spec errors.yaml -embed-messages=false
+
 func init() {
-	DefaultErrorMul.Set(IllegalAccessToken, consts.SimplifiedChinese, "非法token")
-	DefaultErrorMul.Set(IllegalAccessToken, consts.TraditionalChinese, "非法token")
-	DefaultErrorMul.Set(IllegalAccessToken, consts.English, "Illegal token")
+	// zh-tw -> zh-cn -> en -> code
+	DefaultErrorMul.Bundle().
+		SetFallback(consts.TraditionalChinese, consts.SimplifiedChinese).
+		SetFallback(consts.SimplifiedChinese, consts.English)
+	if err := DefaultErrorMul.Bundle().LoadFS(locales, "locales"); err != nil {
+		panic(err)
+	}
+}
 
-	DefaultErrorMul.Set(IllegalCertificate, consts.SimplifiedChinese, "非法凭证")
-	DefaultErrorMul.Set(IllegalCertificate, consts.TraditionalChinese, "非法憑證")
-	DefaultErrorMul.Set(IllegalCertificate, consts.English, "Illegal certificate")
+// ErrorMul error multi-language, messages of codes are resolved through an i18n bundle
+type ErrorMul struct {
+	bundle atomic.Pointer[i18n.Bundle]
+	mu     sync.RWMutex
+	// ids code -> message id, codes without id use the decimal code as id
+	ids map[int]string
+	// watchMu guards base, messages set while watching are written to base too so reloads keep them
+	watchMu sync.Mutex
+	base    *i18n.Bundle
+}
 
-	DefaultErrorMul.Set(IllegalParameter, consts.SimplifiedChinese, "非法参数")
-	DefaultErrorMul.Set(IllegalParameter, consts.TraditionalChinese, "非法參數")
-	DefaultErrorMul.Set(IllegalParameter, consts.English, "Illegal parameter")
+// NewErrorMul new error mul resolving messages through bundle
+func NewErrorMul(bundle *i18n.Bundle) *ErrorMul {
+	e := &ErrorMul{}
+	e.bundle.Store(bundle)
+	return e
+}
 
-	DefaultErrorMul.Set(RecordNotFound, consts.SimplifiedChinese, "记录未找到")
-	DefaultErrorMul.Set(RecordNotFound, consts.TraditionalChinese, "記錄未找到")
-	DefaultErrorMul.Set(RecordNotFound, consts.English, "record not found")
+// Bundle get current bundle, load message files into it to translate codes
+func (e *ErrorMul) Bundle() *i18n.Bundle {
+	if b := e.bundle.Load(); b != nil {
+		return b
+	}
+	e.bundle.CompareAndSwap(nil, i18n.NewBundle(consts.SimplifiedChinese))
+	return e.bundle.Load()
+}
 
-	DefaultErrorMul.Set(AlreadyExists, consts.SimplifiedChinese, "已经存在,不允许重复创建")
-	DefaultErrorMul.Set(AlreadyExists, consts.TraditionalChinese, "已經存在,不允許重複創建")
-	DefaultErrorMul.Set(AlreadyExists, consts.English, "already exists,duplicate creation is not allowed")
+// Swap replace bundle atomically, return the previous one
+func (e *ErrorMul) Swap(bundle *i18n.Bundle) *i18n.Bundle {
+	return e.bundle.Swap(bundle)
+}
 
-	DefaultErrorMul.Set(SortParameterMismatch, consts.SimplifiedChinese, "排序参数不匹配")
-	DefaultErrorMul.Set(SortParameterMismatch, consts.TraditionalChinese, "排序參數不匹配")
-	DefaultErrorMul.Set(SortParameterMismatch, consts.English, "sort parameter mismatch")
+// SetID bind code to message id of the bundle
+func (e *ErrorMul) SetID(key int, id string) *ErrorMul {
+	e.mu.Lock()
+	if e.ids == nil {
+		e.ids = map[int]string{}
+	}
+	e.ids[key] = id
+	e.mu.Unlock()
+	return e
+}
 
+// ID get message id of code
+func (e *ErrorMul) ID(key int) string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if id, ok := e.ids[key]; ok {
+		return id
+	}
+	return strconv.Itoa(key)
 }
 
-// ErrorMul error multi-language
-type ErrorMul struct {
-	data sync.Map
+// key get code of message id
+func (e *ErrorMul) key(id string) (int, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for k, v := range e.ids {
+		if v == id {
+			return k, true
+		}
+	}
+	k, err := strconv.Atoi(id)
+	return k, err == nil
 }
 
-// Set send key lan val to mul
+// Set send key lan val to mul, an empty val removes the translation, panic when lan is not a BCP 47 tag, use SetE to get the error.
+// val is a go template executed with the args of the error, e.g. "{{.Field}} already exists",
+// it is returned as is when rendered without args, so plain messages containing "{{" keep their meaning
 func (e *ErrorMul) Set(key int, lan string, val string) *ErrorMul {
-	if v, ok := e.data.Load(key); !ok {
-		e.data.Store(key, map[string]string{lan: val})
-	} else {
-		v.(map[string]string)[lan] = val
+	if err := e.SetE(key, lan, val); err != nil {
+		panic(err)
 	}
 	return e
 }
 
-// Get send key lan to get value
+// SetE Set returning error when lan is not a BCP 47 tag, e.g. for messages loaded at runtime
+func (e *ErrorMul) SetE(key int, lan string, val string) error {
+	if err := e.addMessage(lan, &i18n.Message{ID: e.ID(key), Other: val}); err != nil {
+		return fmt.Errorf("xerror: message of code %d: %w", key, err)
+	}
+	return nil
+}
+
+// addMessage add m to the current bundle and the base of Watch
+func (e *ErrorMul) addMessage(lan string, m *i18n.Message) error {
+	e.watchMu.Lock()
+	defer e.watchMu.Unlock()
+	if e.base != nil {
+		cp := *m
+		if err := e.base.AddMessages(lan, &cp); err != nil {
+			return err
+		}
+	}
+	return e.Bundle().AddMessages(lan, m)
+}
+
+// Get send key lan to get value, the bundle fallbacks are used when lan has no translation and the code itself at last
 func (e *ErrorMul) Get(key int, lan string) string {
-	if val, ok := e.data.Load(key); ok {
-		return val.(map[string]string)[lan]
+	return e.Localize(key, lan, nil, nil)
+}
+
+// Localize get message of key in lan executed with template data, pluralCount selects the plural form
+func (e *ErrorMul) Localize(key int, lan string, data interface{}, pluralCount interface{}) string {
+	if msg := e.message(key, lan, data, pluralCount); msg != "" {
+		return msg
 	}
-	return ""
+	return strconv.Itoa(key)
 }
 
-// DeleteKey delete key
+// message localized message of key, empty when key has no translation
+func (e *ErrorMul) message(key int, lan string, data interface{}, pluralCount interface{}) string {
+	if data == nil && pluralCount == nil {
+		// 没有参数不执行模板, 否则字段会渲染成 <no value>
+		if m, ok := e.Bundle().Lookup(lan, e.ID(key)); ok {
+			return m.Other
+		}
+		return ""
+	}
+	msg, _ := e.Bundle().Localize(lan, &i18n.LocalizeConfig{
+		MessageID:    e.ID(key),
+		TemplateData: data,
+		PluralCount:  pluralCount,
+	})
+	return msg
+}
+
+// Missing codes missing a translation in any of languages, code -> missing languages, default consts.SupportedLanguages
+func (e *ErrorMul) Missing(languages ...string) map[int][]string {
+	if len(languages) == 0 {
+		languages = consts.SupportedLanguages
+	}
+	ret := map[int][]string{}
+	for id, langs := range e.Bundle().Missing(languages...) {
+		if key, ok := e.key(id); ok {
+			ret[key] = langs
+		}
+	}
+	// 绑定了 id 却没有任何翻译的 code
+	e.mu.RLock()
+	ids := make(map[int]string, len(e.ids))
+	for k, v := range e.ids {
+		ids[k] = v
+	}
+	e.mu.RUnlock()
+	messages := e.Bundle().Messages()
+	for key, id := range ids {
+		if _, ok := messages[id]; !ok {
+			for _, lang := range languages {
+				ret[key] = append(ret[key], i18n.Normalize(lang))
+			}
+		}
+	}
+	return ret
+}
+
+// DeleteKey delete key and its messages
 func (e *ErrorMul) DeleteKey(key int) *ErrorMul {
-	e.data.Delete(key)
+	id := e.ID(key)
+	e.watchMu.Lock()
+	if e.base != nil {
+		e.base.RemoveMessage(id)
+	}
+	e.Bundle().RemoveMessage(id)
+	e.watchMu.Unlock()
+	e.mu.Lock()
+	delete(e.ids, key)
+	e.mu.Unlock()
 	return e
 }
+
+// Range call fn with every key and a copy of its messages, stop when fn return false
+func (e *ErrorMul) Range(fn func(key int, messages map[string]string) bool) {
+	for id, translations := range e.Bundle().Messages() {
+		key, ok := e.key(id)
+		if !ok {
+			continue
+		}
+		messages := map[string]string{}
+		for lan, m := range translations {
+			messages[lan] = m.Other
+		}
+		if !fn(key, messages) {
+			return
+		}
+	}
+}
@@ -18,9 +18,33 @@ func TestSetDefault(t *testing.T) {
 			t.Fatal(err)
 		}
 	}()
+	defer func() {
+		DefaultErrorMul.DeleteKey(ProjectNotBelongToPlatform).DeleteKey(GenPDFFailed)
+	}()
 	DefaultErrorMul.Set(ProjectNotBelongToPlatform, "zh", "项目不属于该平台")
 	DefaultErrorMul.Set(ProjectNotBelongToPlatform, "en", "item does not belong to this platform")
 	DefaultErrorMul.Set(GenPDFFailed, "zh", "生成pdf失败")
 	DefaultErrorMul.Set(GenPDFFailed, "en", "gen pdf failed")
 	assert.Equal(t, NewError(GenPDFFailed, "en").Error(), "gen pdf failed")
 }
+
+func TestSetInvalidLanguage(t *testing.T) {
+	const code = 40009
+	defer DefaultErrorMul.DeleteKey(code)
+	assert.Error(t, DefaultErrorMul.SetE(code, "chinese simplified", "简体"))
+	assert.NoError(t, DefaultErrorMul.SetE(code, "zh_CN", "简体"))
+	assert.Equal(t, "简体", DefaultErrorMul.Get(code, "zh-cn"))
+
+	// Set 可以链式调用, 无效语言 panic
+	DefaultErrorMul.Set(code, "en", "simplified").Set(code, "zh-TW", "繁體")
+	assert.Equal(t, "繁體", DefaultErrorMul.Get(code, "zh-tw"))
+	assert.Panics(t, func() { DefaultErrorMul.Set(code, "chinese simplified", "简体") })
+}
+
+func TestTemplateWithoutArgs(t *testing.T) {
+	const code = 40010
+	defer DefaultErrorMul.DeleteKey(code)
+	assert.NoError(t, DefaultErrorMul.SetE(code, "en", "use {{name}} or {{.Field}}"))
+	assert.Equal(t, "use {{name}} or {{.Field}}", NewError(code, "en").Error())
+	assert.Equal(t, "use {{name}} or {{.Field}}", DefaultErrorMul.Get(code, "en"))
+}
@@ -0,0 +1,150 @@
+package xerror
+
+import (
+	"context"
+	"fmt"
+	"github.com/olongfen/toolkit/consts"
+	"github.com/olongfen/toolkit/i18n"
+	"github.com/olongfen/toolkit/scontext"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestBizErrorUnwrap(t *testing.T) {
+	cause := errors.New("sql: no rows")
+	err := NewError(RecordNotFound, consts.English).WithError(cause)
+	wrapped := fmt.Errorf("get user: %w", errors.WithStack(err))
+
+	assert.True(t, errors.Is(wrapped, cause))
+	assert.True(t, errors.Is(wrapped, NewError(RecordNotFound, consts.SimplifiedChinese)))
+	assert.False(t, errors.Is(wrapped, NewError(AlreadyExists, consts.English)))
+	assert.True(t, IsCode(wrapped, RecordNotFound))
+	assert.False(t, IsCode(cause, RecordNotFound))
+
+	e, ok := FromError(wrapped)
+	assert.True(t, ok)
+	assert.Equal(t, RecordNotFound, e.Code())
+	assert.Equal(t, "record not found", e.Error())
+}
+
+func TestErrorMulBundle(t *testing.T) {
+	assert.Equal(t, "permission denied", NewError(Forbidden, consts.English).Error())
+	assert.Equal(t, "沒有權限執行該操作", DefaultErrorMul.Get(Forbidden, consts.TraditionalChinese))
+	// 未翻译的语言使用默认语言
+	assert.Equal(t, "没有权限执行该操作", DefaultErrorMul.Get(Forbidden, "fr"))
+
+	mul := NewErrorMul(i18n.NewBundle(consts.English)).SetID(42901, "TooManyRequests")
+	assert.NoError(t, mul.Bundle().ParseMessageFileBytes([]byte(`
+TooManyRequests:
+  one: retry after {{.PluralCount}} second
+  other: retry after {{.PluralCount}} seconds
+`), "en.yaml"))
+	mul.Set(42902, consts.English, "quota of {{.Name}} exceeded")
+	assert.Equal(t, "retry after 1 second", mul.Localize(42901, consts.English, nil, 1))
+	assert.Equal(t, "retry after 30 seconds", mul.Localize(42901, consts.English, nil, 30))
+	assert.Equal(t, "quota of api exceeded", mul.Localize(42902, consts.English, map[string]string{"Name": "api"}, nil))
+
+	keys := map[int]bool{}
+	mul.Range(func(key int, messages map[string]string) bool {
+		keys[key] = true
+		return true
+	})
+	assert.Equal(t, map[int]bool{42901: true, 42902: true}, keys)
+	mul.DeleteKey(42902)
+	assert.Equal(t, "42902", NewError(42902, consts.English, mul).Error())
+}
+
+func TestNewErrorf(t *testing.T) {
+	const tooManyItems = 40099
+	assert.NoError(t, DefaultErrorMul.Bundle().ParseMessageFileBytes([]byte(`{"40099": {"one": "max {{.PluralCount}} item of {{.Field}} allowed", "other": "max {{.PluralCount}} items of {{.Field}} allowed"}}`), "en.json"))
+	DefaultErrorMul.Set(tooManyItems, consts.SimplifiedChinese, "{{.Field}} 最多 {{.PluralCount}} 项")
+	defer DefaultErrorMul.DeleteKey(tooManyItems)
+
+	err := NewErrorf(tooManyItems, consts.English, Args{"Field": "tags", PluralCount: 5})
+	assert.Equal(t, "max 5 items of tags allowed", err.Error())
+	assert.Equal(t, Args{"Field": "tags", PluralCount: 5}, err.Args())
+	assert.Equal(t, "max 1 item of tags allowed", NewErrorf(tooManyItems, consts.English, Args{"Field": "tags", PluralCount: 1}).Error())
+	assert.Equal(t, "tags 最多 5 项", NewError(tooManyItems, consts.SimplifiedChinese).WithArgs(Args{"Field": "tags"}).WithArgs(Args{PluralCount: 5}).Error())
+	assert.Nil(t, NewError(Forbidden, consts.English).Args())
+}
+
+func TestGeneratedConstructor(t *testing.T) {
+	ctx := scontext.SetLanguage(context.Background(), consts.English)
+	err := NewRecordNotFound(ctx)
+	assert.Equal(t, RecordNotFound, err.Code())
+	assert.Equal(t, "record not found", err.Error())
+	assert.Equal(t, "记录未找到", NewRecordNotFound(context.Background()).Error())
+}
+
+func TestLazyError(t *testing.T) {
+	const lazy = 40097
+	err := New(lazy, Args{"Field": "email"})
+	assert.Equal(t, "", err.Language())
+	assert.Equal(t, "40097", err.Error())
+
+	// 消息在渲染时翻译, 创建后设置的翻译同样生效
+	DefaultErrorMul.Set(lazy, consts.SimplifiedChinese, "{{.Field}} 已存在")
+	DefaultErrorMul.Set(lazy, consts.English, "{{.Field}} already exists")
+	defer DefaultErrorMul.DeleteKey(lazy)
+	assert.Equal(t, "email 已存在", err.Error())
+	assert.Equal(t, "email already exists", err.Localize(consts.English))
+
+	ctx := scontext.SetLanguage(context.Background(), consts.English)
+	err = NewErrorContext(ctx, RecordNotFound)
+	assert.Equal(t, consts.English, err.Language())
+	assert.Equal(t, "record not found", err.Error())
+	assert.Equal(t, "記錄未找到", err.Localize(consts.TraditionalChinese))
+}
+
+var errShared = NewError(Forbidden, consts.English)
+
+func newForbidden() BizError {
+	return NewError(Forbidden, consts.English)
+}
+
+func TestErrorCopy(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			e := errShared.WithError(fmt.Errorf("cause %d", i)).WithArgs(Args{"N": i}).WithDetails(ResourceInfo{ResourceName: "n"})
+			assert.Equal(t, fmt.Sprintf("cause %d", i), errors.Unwrap(e).Error())
+		}(i)
+	}
+	wg.Wait()
+	assert.Nil(t, errors.Unwrap(errShared))
+	assert.Nil(t, errShared.Args())
+	assert.Nil(t, errShared.Details())
+	assert.Equal(t, "沒有權限執行該操作", errShared.WithLanguage(consts.TraditionalChinese).Error())
+	assert.Equal(t, "permission denied", errShared.Error())
+}
+
+func TestStack(t *testing.T) {
+	e := newForbidden()
+	stack := e.Stack()
+	require.NotEmpty(t, stack)
+	assert.True(t, strings.HasSuffix(stack[0].Function, "xerror.newForbidden"), stack[0].Function)
+	assert.True(t, strings.HasSuffix(stack[1].Function, "xerror.TestStack"), stack[1].Function)
+
+	// 相同调用点的指纹相同, 不同 code 或调用点不同
+	assert.Equal(t, e.Fingerprint(), newForbidden().Fingerprint())
+	assert.Equal(t, e.Fingerprint(), e.WithError(errors.New("cause")).Fingerprint())
+	assert.NotEqual(t, e.Fingerprint(), NewError(Forbidden, consts.English).Fingerprint())
+	assert.NotEqual(t, e.Fingerprint(), NewError(RecordNotFound, consts.English).Fingerprint())
+
+	// 生成的构造函数不计入调用栈
+	stack = NewForbidden(context.Background()).Stack()
+	require.NotEmpty(t, stack)
+	assert.True(t, strings.HasSuffix(stack[0].Function, "xerror.TestStack"), stack[0].Function)
+
+	SetCaptureStack(false)
+	defer SetCaptureStack(true)
+	assert.False(t, CaptureStack())
+	assert.Empty(t, newForbidden().Stack())
+	assert.Len(t, newForbidden().Fingerprint(), 16)
+}
@@ -0,0 +1,92 @@
+// Code generated by xerrgen from errors.yaml. DO NOT EDIT.
+
+package xerror
+
+import (
+	"context"
+)
+
+const (
+	// MessageSuccess 成功
+	MessageSuccess MessageID = "toolkit.Success"
+	// MessageFailed 失败
+	MessageFailed MessageID = "toolkit.Failed"
+	// MessageCreated 创建成功
+	MessageCreated MessageID = "toolkit.Created"
+	// MessageUpdated 更新成功
+	MessageUpdated MessageID = "toolkit.Updated"
+	// MessageDeleted 删除成功
+	MessageDeleted MessageID = "toolkit.Deleted"
+)
+
+const (
+	// IllegalAccessToken 非法token
+	IllegalAccessToken = 40001
+	// IllegalCertificate 非法凭证
+	IllegalCertificate = 40002
+	// IllegalParameter 非法参数
+	IllegalParameter = 40003
+	// RecordNotFound 找不到记录
+	RecordNotFound = 40004
+	// AlreadyExists 已经存在
+	AlreadyExists = 40005
+	// SortParameterMismatch 排序参数不匹配
+	SortParameterMismatch = 40006
+	// Forbidden 无权限
+	Forbidden = 40301
+	// PreconditionFailed 资源已被修改
+	PreconditionFailed = 41201
+)
+
+func init() {
+	Register(
+		Definition{Code: IllegalAccessToken, Namespace: "toolkit", Name: "IllegalAccessToken", HTTPStatus: 401, GRPCCode: 16, Severity: SeverityWarning},
+		Definition{Code: IllegalCertificate, Namespace: "toolkit", Name: "IllegalCertificate", HTTPStatus: 401, GRPCCode: 16, Severity: SeverityWarning},
+		Definition{Code: IllegalParameter, Namespace: "toolkit", Name: "IllegalParameter", HTTPStatus: 400, GRPCCode: 3, Severity: SeverityInfo},
+		Definition{Code: RecordNotFound, Namespace: "toolkit", Name: "RecordNotFound", HTTPStatus: 404, GRPCCode: 5, Severity: SeverityInfo},
+		Definition{Code: AlreadyExists, Namespace: "toolkit", Name: "AlreadyExists", HTTPStatus: 409, GRPCCode: 6, Severity: SeverityInfo},
+		Definition{Code: SortParameterMismatch, Namespace: "toolkit", Name: "SortParameterMismatch", HTTPStatus: 400, GRPCCode: 3, Severity: SeverityInfo},
+		Definition{Code: Forbidden, Namespace: "toolkit", Name: "Forbidden", HTTPStatus: 403, GRPCCode: 7, Severity: SeverityWarning},
+		Definition{Code: PreconditionFailed, Namespace: "toolkit", Name: "PreconditionFailed", HTTPStatus: 412, GRPCCode: 9, Severity: SeverityInfo, Retryable: true},
+	)
+}
+
+// NewIllegalAccessToken new IllegalAccessToken in the language of ctx, 非法token
+func NewIllegalAccessToken(ctx context.Context, args ...Args) BizError {
+	return NewErrorContextSkip(ctx, 1, IllegalAccessToken, args...)
+}
+
+// NewIllegalCertificate new IllegalCertificate in the language of ctx, 非法凭证
+func NewIllegalCertificate(ctx context.Context, args ...Args) BizError {
+	return NewErrorContextSkip(ctx, 1, IllegalCertificate, args...)
+}
+
+// NewIllegalParameter new IllegalParameter in the language of ctx, 非法参数
+func NewIllegalParameter(ctx context.Context, args ...Args) BizError {
+	return NewErrorContextSkip(ctx, 1, IllegalParameter, args...)
+}
+
+// NewRecordNotFound new RecordNotFound in the language of ctx, 找不到记录
+func NewRecordNotFound(ctx context.Context, args ...Args) BizError {
+	return NewErrorContextSkip(ctx, 1, RecordNotFound, args...)
+}
+
+// NewAlreadyExists new AlreadyExists in the language of ctx, 已经存在
+func NewAlreadyExists(ctx context.Context, args ...Args) BizError {
+	return NewErrorContextSkip(ctx, 1, AlreadyExists, args...)
+}
+
+// NewSortParameterMismatch new SortParameterMismatch in the language of ctx, 排序参数不匹配
+func NewSortParameterMismatch(ctx context.Context, args ...Args) BizError {
+	return NewErrorContextSkip(ctx, 1, SortParameterMismatch, args...)
+}
+
+// NewForbidden new Forbidden in the language of ctx, 无权限
+func NewForbidden(ctx context.Context, args ...Args) BizError {
+	return NewErrorContextSkip(ctx, 1, Forbidden, args...)
+}
+
+// NewPreconditionFailed new PreconditionFailed in the language of ctx, 资源已被修改
+func NewPreconditionFailed(ctx context.Context, args ...Args) BizError {
+	return NewErrorContextSkip(ctx, 1, PreconditionFailed, args...)
+}
@@ -1,15 +0,0 @@
-package xerror
-
-const (
-	IllegalAccessToken = 40001
-	// IllegalCertificate 非法凭证
-	IllegalCertificate = 40002
-	// IllegalParameter 非法参数
-	IllegalParameter = 40003
-	// RecordNotFound 找不到记录
-	RecordNotFound = 40004
-	// AlreadyExists 已经存在
-	AlreadyExists = 40005
-	// SortParameterMismatch 排序参数不匹配
-	SortParameterMismatch = 40006
-)
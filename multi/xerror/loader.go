@@ -0,0 +1,217 @@
+package xerror
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"github.com/olongfen/toolkit/i18n"
+	"gorm.io/gorm"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// Loader load messages of mul into bundle
+type Loader interface {
+	Load(ctx context.Context, mul *ErrorMul, bundle *i18n.Bundle) error
+}
+
+// Versioned loader reporting a version of its source, Watch reloads only when a version changes
+type Versioned interface {
+	Version(ctx context.Context) (string, error)
+}
+
+// Reload load loaders into a clone of base and swap it in, concurrent Get calls see either the old or the new catalogue
+func (e *ErrorMul) Reload(ctx context.Context, base *i18n.Bundle, loaders ...Loader) error {
+	next := base.Clone()
+	for _, l := range loaders {
+		if err := l.Load(ctx, e, next); err != nil {
+			return err
+		}
+	}
+	e.Swap(next)
+	return nil
+}
+
+// WatchConfig watch config
+type WatchConfig struct {
+	// Interval poll interval, default 5s
+	Interval time.Duration
+	// OnError called when polling or reloading fails, the current catalogue is kept
+	OnError func(err error)
+}
+
+// Watch load loaders over the current catalogue then poll them until ctx is done, one Watch per ErrorMul.
+// Every reload starts from the catalogue as it was when Watch started plus the messages set and deleted since,
+// so messages removed from the sources fall back to it
+func (e *ErrorMul) Watch(ctx context.Context, loaders []Loader, config ...WatchConfig) error {
+	var (
+		conf WatchConfig
+	)
+	if len(config) > 0 {
+		conf = config[0]
+	}
+	if conf.Interval <= 0 {
+		conf.Interval = 5 * time.Second
+	}
+	version, err := versionOf(ctx, loaders)
+	if err != nil {
+		return err
+	}
+	e.watchMu.Lock()
+	e.base = e.Bundle().Clone()
+	e.watchMu.Unlock()
+	if err = e.reloadBase(ctx, loaders); err != nil {
+		e.stopWatch()
+		return err
+	}
+	go func() {
+		ticker := time.NewTicker(conf.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				e.stopWatch()
+				return
+			case <-ticker.C:
+			}
+			v, err := versionOf(ctx, loaders)
+			if err == nil && v == version {
+				continue
+			}
+			if err == nil {
+				err = e.reloadBase(ctx, loaders)
+			}
+			if err != nil {
+				if conf.OnError != nil {
+					conf.OnError(err)
+				}
+				continue
+			}
+			version = v
+		}
+	}()
+	return nil
+}
+
+// reloadBase reload over the base of Watch, Set and DeleteKey wait until the new catalogue is swapped in
+func (e *ErrorMul) reloadBase(ctx context.Context, loaders []Loader) error {
+	e.watchMu.Lock()
+	defer e.watchMu.Unlock()
+	return e.Reload(ctx, e.base, loaders...)
+}
+
+func (e *ErrorMul) stopWatch() {
+	e.watchMu.Lock()
+	e.base = nil
+	e.watchMu.Unlock()
+}
+
+// versionOf combined version of loaders, loaders without version change every time
+func versionOf(ctx context.Context, loaders []Loader) (string, error) {
+	h := sha1.New()
+	for _, l := range loaders {
+		v, ok := l.(Versioned)
+		if !ok {
+			return time.Now().String(), nil
+		}
+		s, err := v.Version(ctx)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s;", s)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// DirLoader per-language message files of Dir such as en.yaml or zh-CN.json, keys are message ids, e.g. "toolkit.RecordNotFound"
+type DirLoader struct {
+	Dir string
+}
+
+var _ Versioned = DirLoader{}
+
+// Load load every message file of dir
+func (l DirLoader) Load(ctx context.Context, mul *ErrorMul, bundle *i18n.Bundle) error {
+	return bundle.LoadFS(os.DirFS(l.Dir), ".")
+}
+
+// Version name, size and modification time of every message file
+func (l DirLoader) Version(ctx context.Context) (string, error) {
+	h := sha1.New()
+	err := fs.WalkDir(os.DirFS(l.Dir), ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !i18n.IsMessageFile(p) {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s:%d:%d;", p, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ErrorMessage error message table of DBLoader
+type ErrorMessage struct {
+	ID        uint      `gorm:"primarykey;autoIncrement"`
+	Code      int       `gorm:"uniqueIndex:idx_error_message;comment:错误码"`
+	Language  string    `gorm:"size:16;uniqueIndex:idx_error_message;comment:语言"`
+	Message   string    `gorm:"size:512;comment:消息模板"`
+	UpdatedAt time.Time `gorm:"comment:更新时间"`
+}
+
+// DB db provider, satisfied by db_data.DBData
+type DB interface {
+	DB(ctx context.Context) *gorm.DB
+}
+
+// DBLoader messages of the ErrorMessage table
+type DBLoader struct {
+	Data DB
+}
+
+var _ Versioned = DBLoader{}
+
+// Load load every row as the message of its code and language
+func (l DBLoader) Load(ctx context.Context, mul *ErrorMul, bundle *i18n.Bundle) error {
+	var (
+		rows []ErrorMessage
+	)
+	if err := l.Data.DB(ctx).Model(&ErrorMessage{}).Find(&rows).Error; err != nil {
+		return err
+	}
+	return addRows(mul, bundle, rows)
+}
+
+func addRows(mul *ErrorMul, bundle *i18n.Bundle, rows []ErrorMessage) error {
+	for _, row := range rows {
+		if err := bundle.AddMessages(row.Language, &i18n.Message{ID: mul.ID(row.Code), Other: row.Message}); err != nil {
+			return fmt.Errorf("xerror: message of code %d: %w", row.Code, err)
+		}
+	}
+	return nil
+}
+
+// Version row count and latest update time, queried without dialect specific sql
+func (l DBLoader) Version(ctx context.Context) (string, error) {
+	var (
+		count  int64
+		latest ErrorMessage
+		db     = l.Data.DB(ctx)
+	)
+	if err := db.Model(&ErrorMessage{}).Count(&count).Error; err != nil {
+		return "", err
+	}
+	if count > 0 {
+		if err := db.Model(&ErrorMessage{}).Select("updated_at").Order("updated_at DESC").Limit(1).Find(&latest).Error; err != nil {
+			return "", err
+		}
+	}
+	return fmt.Sprintf("%d:%s", count, latest.UpdatedAt.UTC().Format(time.RFC3339Nano)), nil
+}
@@ -0,0 +1,129 @@
+package xerror
+
+import (
+	"context"
+	"github.com/glebarez/sqlite"
+	"github.com/olongfen/toolkit/consts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWatchDir(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "en.json")
+	require.NoError(t, os.WriteFile(file, []byte(`{"toolkit": {"RecordNotFound": "no such record"}}`), 0o644))
+
+	mul := NewErrorMul(DefaultErrorMul.Bundle().Clone()).SetID(RecordNotFound, "toolkit.RecordNotFound")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var (
+		errs []error
+		mu   sync.Mutex
+	)
+	require.NoError(t, mul.Watch(ctx, []Loader{DirLoader{Dir: dir}}, WatchConfig{
+		Interval: 10 * time.Millisecond,
+		OnError: func(err error) {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		},
+	}))
+	assert.Equal(t, "no such record", mul.Get(RecordNotFound, consts.English))
+	assert.Equal(t, "记录未找到", mul.Get(RecordNotFound, consts.SimplifiedChinese))
+
+	// 并发读取不会看到加载一半的消息
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			msg := mul.Get(RecordNotFound, consts.English)
+			if msg != "no such record" && msg != "record is gone" && msg != "record not found" {
+				t.Errorf("unexpected message %q", msg)
+				return
+			}
+		}
+	}()
+	require.NoError(t, os.WriteFile(file, []byte(`{"toolkit.RecordNotFound": "record is gone"}`), 0o644))
+	require.NoError(t, os.Chtimes(file, time.Now(), time.Now().Add(time.Second)))
+	assert.Eventually(t, func() bool {
+		return mul.Get(RecordNotFound, consts.English) == "record is gone"
+	}, time.Second, 10*time.Millisecond)
+	<-done
+
+	require.NoError(t, os.Remove(file))
+	assert.Eventually(t, func() bool {
+		return mul.Get(RecordNotFound, consts.English) == "record not found"
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "en.yaml"), []byte("toolkit: [broken"), 0o644))
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(errs) > 0
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "record not found", mul.Get(RecordNotFound, consts.English))
+}
+
+func TestDBLoaderRows(t *testing.T) {
+	mul := NewErrorMul(DefaultErrorMul.Bundle().Clone()).SetID(Forbidden, "toolkit.Forbidden")
+	next := mul.Bundle().Clone()
+	require.NoError(t, addRows(mul, next, []ErrorMessage{
+		{Code: Forbidden, Language: "en", Message: "access denied"},
+		{Code: 40999, Language: "zh-CN", Message: "自定义"},
+	}))
+	assert.Equal(t, "permission denied", mul.Get(Forbidden, consts.English))
+	mul.Swap(next)
+	assert.Equal(t, "access denied", mul.Get(Forbidden, consts.English))
+	assert.Equal(t, "自定义", mul.Get(40999, consts.SimplifiedChinese))
+	assert.Error(t, addRows(mul, next, []ErrorMessage{{Code: 40999, Language: "!bad", Message: "x"}}))
+}
+
+type testDB struct {
+	db *gorm.DB
+}
+
+func (d testDB) DB(ctx context.Context) *gorm.DB {
+	return d.db.WithContext(ctx)
+}
+
+func TestDBLoader(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "messages.db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&ErrorMessage{}))
+	loader := DBLoader{Data: testDB{db: db}}
+
+	ctx := context.Background()
+	empty, err := loader.Version(ctx)
+	require.NoError(t, err)
+	row := ErrorMessage{Code: Forbidden, Language: "en", Message: "access denied"}
+	require.NoError(t, db.Create(&row).Error)
+	version, err := loader.Version(ctx)
+	require.NoError(t, err)
+	assert.NotEqual(t, empty, version)
+
+	mul := NewErrorMul(DefaultErrorMul.Bundle().Clone()).SetID(Forbidden, "toolkit.Forbidden").SetID(RecordNotFound, "toolkit.RecordNotFound")
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	require.NoError(t, mul.Watch(watchCtx, []Loader{loader}, WatchConfig{Interval: 10 * time.Millisecond}))
+	assert.Equal(t, "access denied", mul.Get(Forbidden, consts.English))
+
+	// Watch 开始后设置与删除的消息在重新加载后仍然有效
+	require.NoError(t, mul.SetE(40998, consts.English, "set after watch"))
+	mul.DeleteKey(RecordNotFound)
+	require.NoError(t, db.Model(&row).Updates(ErrorMessage{Message: "no access", UpdatedAt: time.Now().Add(time.Second)}).Error)
+	next, err := loader.Version(ctx)
+	require.NoError(t, err)
+	assert.NotEqual(t, version, next)
+	assert.Eventually(t, func() bool {
+		return mul.Get(Forbidden, consts.English) == "no access"
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "set after watch", mul.Get(40998, consts.English))
+	_, ok := mul.Bundle().Lookup(consts.English, "toolkit.RecordNotFound")
+	assert.False(t, ok)
+}
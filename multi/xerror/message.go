@@ -0,0 +1,50 @@
+package xerror
+
+import (
+	"fmt"
+	"github.com/olongfen/toolkit/consts"
+	"github.com/olongfen/toolkit/i18n"
+)
+
+// 信封的 code, 不是注册的错误码
+const (
+	// Success code of successful responses
+	Success = 0
+	// Failed code of errors without a biz code
+	Failed = -1
+)
+
+// MessageID id of an envelope message in the bundle, e.g. MessageCreated, envelope messages are not error codes
+type MessageID string
+
+// SetMessage send id lan val to mul, an empty val removes the translation, return error when lan is not a BCP 47 tag
+func (e *ErrorMul) SetMessage(id MessageID, lan string, val string) error {
+	if err := e.addMessage(lan, &i18n.Message{ID: string(id), Other: val}); err != nil {
+		return fmt.Errorf("xerror: message %s: %w", id, err)
+	}
+	return nil
+}
+
+// Message get envelope message of id in lan, the bundle fallbacks are used when lan has no translation and the id itself at last,
+// envelope messages are not templates
+func (e *ErrorMul) Message(id MessageID, lan string) string {
+	if m, ok := e.Bundle().Lookup(lan, string(id)); ok && m.Other != "" {
+		return m.Other
+	}
+	return string(id)
+}
+
+// MissingMessages envelope messages missing a translation in any of languages, id -> missing languages,
+// default consts.SupportedLanguages
+func (e *ErrorMul) MissingMessages(languages ...string) map[MessageID][]string {
+	if len(languages) == 0 {
+		languages = consts.SupportedLanguages
+	}
+	ret := map[MessageID][]string{}
+	for id, langs := range e.Bundle().Missing(languages...) {
+		if _, ok := e.key(id); !ok {
+			ret[MessageID(id)] = langs
+		}
+	}
+	return ret
+}
@@ -0,0 +1,143 @@
+package xerror
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Severity severity of an error code
+type Severity int
+
+const (
+	// SeverityInfo expected business outcome, e.g. not found
+	SeverityInfo Severity = iota
+	// SeverityWarning client mistake worth watching, e.g. illegal token
+	SeverityWarning
+	// SeverityError server side failure
+	SeverityError
+	// SeverityCritical failure that needs immediate attention
+	SeverityCritical
+)
+
+var severityNames = []string{"info", "warning", "error", "critical"}
+
+func (s Severity) String() string {
+	if s < 0 || int(s) >= len(severityNames) {
+		return fmt.Sprintf("severity(%d)", int(s))
+	}
+	return severityNames[s]
+}
+
+// MarshalText marshal as name
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Definition error code definition
+type Definition struct {
+	Code int `json:"code"`
+	// Namespace module declaring the code, e.g. "toolkit"
+	Namespace string `json:"namespace"`
+	// Name unique name in namespace, e.g. "RecordNotFound"
+	Name string `json:"name"`
+	// HTTPStatus http status in problem mode, 0 falls back to 400
+	HTTPStatus int `json:"http_status,omitempty"`
+	// GRPCCode value of google.golang.org/grpc/codes.Code, 0 is OK and means unspecified
+	GRPCCode  uint32   `json:"grpc_code,omitempty"`
+	Severity  Severity `json:"severity"`
+	Retryable bool     `json:"retryable"`
+	// Messages default message templates, language -> message, message files loaded later override them
+	Messages map[string]string `json:"messages,omitempty"`
+}
+
+// ID message id of code, "namespace.name"
+func (d Definition) ID() string {
+	return d.Namespace + "." + d.Name
+}
+
+// Registry error code registry, every code is declared once with a unique namespace and name
+type Registry struct {
+	mu    sync.RWMutex
+	mul   *ErrorMul
+	defs  map[int]Definition
+	names map[string]int
+}
+
+var (
+	// DefaultRegistry registry of DefaultErrorMul
+	DefaultRegistry = NewRegistry(DefaultErrorMul)
+)
+
+// NewRegistry new registry binding codes to message ids of mul
+func NewRegistry(mul *ErrorMul) *Registry {
+	return &Registry{
+		mul:   mul,
+		defs:  map[int]Definition{},
+		names: map[string]int{},
+	}
+}
+
+// Register declare codes, panic when a code or namespace.name is already registered, a code already has messages set
+// by ErrorMul.Set without registration, or a message language is not a BCP 47 tag
+func (r *Registry) Register(defs ...Definition) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	messages := r.mul.Bundle().Messages()
+	for _, d := range defs {
+		if d.Namespace == "" || d.Name == "" {
+			panic(fmt.Sprintf("xerror: code %d registered without namespace or name", d.Code))
+		}
+		if old, ok := r.defs[d.Code]; ok {
+			panic(fmt.Sprintf("xerror: code %d of %s already registered by %s", d.Code, d.ID(), old.ID()))
+		}
+		if code, ok := r.names[d.ID()]; ok {
+			panic(fmt.Sprintf("xerror: %s of code %d already registered by code %d", d.ID(), d.Code, code))
+		}
+		// 未注册就用 ErrorMul.Set 设置过消息的 code 已被占用
+		if id := r.mul.ID(d.Code); id == strconv.Itoa(d.Code) && messages[id] != nil {
+			panic(fmt.Sprintf("xerror: code %d of %s already used by messages set without registration", d.Code, d.ID()))
+		}
+		r.defs[d.Code] = d
+		r.names[d.ID()] = d.Code
+		r.mul.SetID(d.Code, d.ID())
+		for lan, msg := range d.Messages {
+			if err := r.mul.SetE(d.Code, lan, msg); err != nil {
+				panic(err.Error())
+			}
+		}
+	}
+}
+
+// Lookup get definition of code
+func (r *Registry) Lookup(code int) (Definition, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	d, ok := r.defs[code]
+	return d, ok
+}
+
+// List every definition ordered by code
+func (r *Registry) List() []Definition {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ret := make([]Definition, 0, len(r.defs))
+	for _, d := range r.defs {
+		ret = append(ret, d)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Code < ret[j].Code
+	})
+	return ret
+}
+
+// Register declare codes in DefaultRegistry
+func Register(defs ...Definition) {
+	DefaultRegistry.Register(defs...)
+}
+
+// Lookup get definition of code in DefaultRegistry
+func Lookup(code int) (Definition, bool) {
+	return DefaultRegistry.Lookup(code)
+}
@@ -0,0 +1,52 @@
+package xerror
+
+import (
+	"github.com/olongfen/toolkit/consts"
+	"github.com/olongfen/toolkit/i18n"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	mul := NewErrorMul(i18n.NewBundle(consts.English))
+	r := NewRegistry(mul)
+	r.Register(
+		Definition{Code: 50301, Namespace: "billing", Name: "Unavailable", HTTPStatus: http.StatusServiceUnavailable, GRPCCode: 14,
+			Severity: SeverityError, Retryable: true, Messages: map[string]string{"en": "billing unavailable", "zh-CN": "计费服务不可用"}},
+		Definition{Code: 40401, Namespace: "billing", Name: "InvoiceNotFound", HTTPStatus: http.StatusNotFound},
+	)
+	assert.PanicsWithValue(t, "xerror: code 50301 of orders.Unavailable already registered by billing.Unavailable", func() {
+		r.Register(Definition{Code: 50301, Namespace: "orders", Name: "Unavailable"})
+	})
+	assert.PanicsWithValue(t, "xerror: billing.Unavailable of code 50302 already registered by code 50301", func() {
+		r.Register(Definition{Code: 50302, Namespace: "billing", Name: "Unavailable"})
+	})
+	assert.Panics(t, func() {
+		r.Register(Definition{Code: 50303})
+	})
+	assert.NoError(t, mul.SetE(50304, "en", "set without registration"))
+	assert.PanicsWithValue(t, "xerror: code 50304 of orders.Busy already used by messages set without registration", func() {
+		r.Register(Definition{Code: 50304, Namespace: "orders", Name: "Busy"})
+	})
+
+	def, ok := r.Lookup(50301)
+	assert.True(t, ok)
+	assert.Equal(t, "billing.Unavailable", def.ID())
+	assert.Equal(t, "error", def.Severity.String())
+	_, ok = r.Lookup(50302)
+	assert.False(t, ok)
+	list := r.List()
+	if assert.Len(t, list, 2) {
+		assert.Equal(t, 40401, list[0].Code)
+	}
+	assert.Equal(t, "billing.Unavailable", mul.ID(50301))
+	assert.Equal(t, "计费服务不可用", NewError(50301, consts.SimplifiedChinese, mul).Error())
+
+	def, ok = Lookup(RecordNotFound)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusNotFound, def.HTTPStatus)
+	assert.Panics(t, func() {
+		Register(Definition{Code: Forbidden, Namespace: "app", Name: "Forbidden"})
+	})
+}
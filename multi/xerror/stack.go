@@ -0,0 +1,84 @@
+package xerror
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"runtime"
+	"sync/atomic"
+)
+
+// noStack 零值表示采集调用栈, 可在运行时并发切换
+var noStack atomic.Bool
+
+// SetCaptureStack capture the call site of every new biz error, enabled by default,
+// disable it on hot paths where the stack is never logged
+func SetCaptureStack(enabled bool) {
+	noStack.Store(!enabled)
+}
+
+// CaptureStack whether new biz errors capture their call site
+func CaptureStack() bool {
+	return !noStack.Load()
+}
+
+const (
+	// stackDepth max frames of a captured stack
+	stackDepth = 32
+	// fingerprintFrames top frames of the fingerprint
+	fingerprintFrames = 3
+)
+
+// callers program counters of the caller skip frames above the function calling callers
+func callers(skip int) []uintptr {
+	if !CaptureStack() {
+		return nil
+	}
+	pcs := make([]uintptr, stackDepth)
+	// 跳过 runtime.Callers 与 callers
+	n := runtime.Callers(skip+2, pcs)
+	return pcs[:n]
+}
+
+// Frame stack frame
+type Frame struct {
+	Function string
+	File     string
+	Line     int
+}
+
+// String "function (file:line)"
+func (f Frame) String() string {
+	return fmt.Sprintf("%s (%s:%d)", f.Function, f.File, f.Line)
+}
+
+func (e *bizError) Stack() []Frame {
+	if len(e.pcs) == 0 {
+		return nil
+	}
+	var (
+		ret    = make([]Frame, 0, len(e.pcs))
+		frames = runtime.CallersFrames(e.pcs)
+	)
+	for {
+		f, more := frames.Next()
+		ret = append(ret, Frame{Function: f.Function, File: f.File, Line: f.Line})
+		if !more {
+			break
+		}
+	}
+	return ret
+}
+
+// Fingerprint hash of the code and functions of the top frames, line numbers are left out so it survives unrelated edits
+func (e *bizError) Fingerprint() string {
+	h := sha1.New()
+	fmt.Fprintf(h, "%d", e.code)
+	for i, f := range e.Stack() {
+		if i == fingerprintFrames {
+			break
+		}
+		fmt.Fprintf(h, ";%s", f.Function)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
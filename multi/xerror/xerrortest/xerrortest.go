@@ -0,0 +1,50 @@
+package xerrortest
+
+import (
+	"fmt"
+	"github.com/olongfen/toolkit/multi/xerror"
+	"sort"
+	"strings"
+)
+
+// TestingT subset of testing.TB used by the assertions
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// AssertComplete assert every code and envelope message of mul has a translation in languages,
+// default consts.SupportedLanguages
+func AssertComplete(t TestingT, mul *xerror.ErrorMul, languages ...string) bool {
+	t.Helper()
+	var (
+		missing  = mul.Missing(languages...)
+		messages = mul.MissingMessages(languages...)
+	)
+	if len(missing) == 0 && len(messages) == 0 {
+		return true
+	}
+	keys := make([]int, 0, len(missing))
+	for k := range missing {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		langs := missing[k]
+		sort.Strings(langs)
+		fmt.Fprintf(&b, "\n\t%d (%s): %s", k, mul.ID(k), strings.Join(langs, ", "))
+	}
+	ids := make([]string, 0, len(messages))
+	for id := range messages {
+		ids = append(ids, string(id))
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		langs := messages[xerror.MessageID(id)]
+		sort.Strings(langs)
+		fmt.Fprintf(&b, "\n\t%s: %s", id, strings.Join(langs, ", "))
+	}
+	t.Errorf("xerror: %d codes and %d messages missing translations:%s", len(keys), len(ids), b.String())
+	return false
+}
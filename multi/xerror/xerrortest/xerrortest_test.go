@@ -0,0 +1,39 @@
+package xerrortest
+
+import (
+	"fmt"
+	"github.com/olongfen/toolkit/consts"
+	"github.com/olongfen/toolkit/i18n"
+	"github.com/olongfen/toolkit/multi/xerror"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type recorder struct {
+	messages []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.messages = append(r.messages, fmt.Sprintf(format, args...))
+}
+
+func TestAssertComplete(t *testing.T) {
+	AssertComplete(t, xerror.DefaultErrorMul)
+
+	mul := xerror.NewErrorMul(i18n.NewBundle(consts.English)).SetID(50001, "Unavailable")
+	mul.Set(42901, "zh", "请求过于频繁")
+	mul.Set(42901, "en-US", "too many requests")
+	mul.SetMessage("Accepted", "en", "accepted")
+
+	r := &recorder{}
+	assert.False(t, AssertComplete(r, mul))
+	if assert.Len(t, r.messages, 1) {
+		assert.Contains(t, r.messages[0], "42901 (42901): zh-tw")
+		assert.Contains(t, r.messages[0], "50001 (Unavailable): en, zh-cn, zh-tw")
+		assert.Contains(t, r.messages[0], "Accepted: zh-cn, zh-tw")
+	}
+	mul.SetMessage("Accepted", "zh", "已接受")
+	assert.True(t, AssertComplete(t, mul.DeleteKey(50001), consts.SimplifiedChinese, consts.English))
+}
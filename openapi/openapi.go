@@ -0,0 +1,376 @@
+package openapi
+
+import (
+	fiber "github.com/gofiber/fiber/v2"
+	"github.com/olongfen/toolkit/multi/xerror"
+	"github.com/olongfen/toolkit/response"
+	"github.com/olongfen/toolkit/tools"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Version OpenAPI version of generated documents
+const Version = "3.1.0"
+
+// Route route documentation
+type Route struct {
+	OperationID string
+	Summary     string
+	Description string
+	Tags        []string
+	Deprecated  bool
+}
+
+// Document OpenAPI document
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+	// ErrorCatalogue every error code of xerror.DefaultErrorMul with its messages
+	ErrorCatalogue []ErrorCode `json:"x-error-catalogue,omitempty"`
+}
+
+// Info document info
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Components document components
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// Operation path operation
+type Operation struct {
+	OperationID string                     `json:"operationId,omitempty"`
+	Summary     string                     `json:"summary,omitempty"`
+	Description string                     `json:"description,omitempty"`
+	Tags        []string                   `json:"tags,omitempty"`
+	Deprecated  bool                       `json:"deprecated,omitempty"`
+	Parameters  []*Parameter               `json:"parameters,omitempty"`
+	RequestBody *RequestBody               `json:"requestBody,omitempty"`
+	Responses   map[string]*OperationReply `json:"responses"`
+}
+
+// Parameter operation parameter
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody operation request body
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// OperationReply operation response
+type OperationReply struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// MediaType media type
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// ErrorCode error code with its messages, language -> message, and its definition in xerror.DefaultRegistry
+type ErrorCode struct {
+	Code       int               `json:"code"`
+	Namespace  string            `json:"namespace,omitempty"`
+	Name       string            `json:"name,omitempty"`
+	HTTPStatus int               `json:"http_status,omitempty"`
+	Severity   string            `json:"severity,omitempty"`
+	Retryable  bool              `json:"retryable,omitempty"`
+	Messages   map[string]string `json:"messages"`
+}
+
+type route struct {
+	method string
+	path   string
+	doc    Route
+	req    reflect.Type
+	resp   reflect.Type
+}
+
+// Registry route registry used to generate the OpenAPI document
+type Registry struct {
+	mu     sync.RWMutex
+	info   Info
+	routes []route
+}
+
+// NewRegistry new registry
+func NewRegistry(title, version string) *Registry {
+	return &Registry{info: Info{Title: title, Version: version}}
+}
+
+// SetDescription set document description
+func (r *Registry) SetDescription(desc string) *Registry {
+	r.info.Description = desc
+	return r
+}
+
+// Register document route taking Req and answering Resp in the response envelope
+func Register[Req, Resp any](r *Registry, method, path string, doc Route) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes = append(r.routes, route{
+		method: strings.ToUpper(method),
+		path:   path,
+		doc:    doc,
+		req:    reflect.TypeOf((*Req)(nil)).Elem(),
+		resp:   reflect.TypeOf((*Resp)(nil)).Elem(),
+	})
+}
+
+// Handle add handler to router and document it, Req is parsed from params, query (GET, HEAD, DELETE)
+// or body and validated by tools.Validate, Resp is answered by response.Success
+func Handle[Req, Resp any](r *Registry, router fiber.Router, method, path string, doc Route,
+	handler func(c *fiber.Ctx, req *Req) (Resp, error)) {
+	prefix := ""
+	if g, ok := router.(*fiber.Group); ok {
+		prefix = g.Prefix
+	}
+	Register[Req, Resp](r, method, prefix+path, doc)
+	router.Add(strings.ToUpper(method), path, func(c *fiber.Ctx) error {
+		var req Req
+		if err := bind(c, &req); err != nil {
+			return xerror.NewErrorContext(c.UserContext(), xerror.IllegalParameter).WithError(err)
+		}
+		if reflect.TypeOf((*Req)(nil)).Elem().Kind() == reflect.Struct {
+			if err := tools.Validate(c.UserContext(), &req); err != nil {
+				return err
+			}
+		}
+		resp, err := handler(c, &req)
+		if err != nil {
+			return err
+		}
+		return response.Success(c, resp)
+	})
+}
+
+func bind(c *fiber.Ctx, req interface{}) error {
+	t := reflect.TypeOf(req).Elem()
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	if t.NumField() == 0 {
+		return nil
+	}
+	if err := c.ParamsParser(req); err != nil {
+		return err
+	}
+	switch c.Method() {
+	case fiber.MethodGet, fiber.MethodHead, fiber.MethodDelete:
+		return c.QueryParser(req)
+	}
+	if len(c.Body()) == 0 {
+		return nil
+	}
+	return c.BodyParser(req)
+}
+
+// Handler serve the document as json
+func (r *Registry) Handler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return c.JSON(r.Document())
+	}
+}
+
+// Document generate OpenAPI document
+func (r *Registry) Document() *Document {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	g := newSchemaGen()
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    r.info,
+		Paths:   map[string]map[string]*Operation{},
+	}
+	g.components["ValidateError"] = &Schema{
+		Type:                 "object",
+		Description:          "field -> localized validation message, returned by tools.ValidateForm and tools.Validate",
+		AdditionalProperties: &Schema{Type: "string"},
+	}
+	doc.ErrorCatalogue = errorCatalogue()
+	codes := make([]interface{}, 0, len(doc.ErrorCatalogue))
+	for _, v := range doc.ErrorCatalogue {
+		codes = append(codes, v.Code)
+	}
+	g.components["ErrorCode"] = &Schema{
+		Type:        "integer",
+		Description: "business error code, see x-error-catalogue for the messages in every language",
+		Enum:        codes,
+	}
+	g.components["ErrorResponse"] = envelope(&Schema{Type: "null"}, &Schema{
+		OneOf: []*Schema{
+			{Ref: "#/components/schemas/ValidateError"},
+			{Type: "object", Properties: map[string]*Schema{"error": {Type: "string"}}},
+		},
+	}, &Schema{OneOf: []*Schema{{Ref: "#/components/schemas/ErrorCode"}, {Type: "integer"}}})
+	// schemaOf 把 Problem 注册到 components, 返回的是 $ref
+	_ = g.schemaOf(reflect.TypeOf(response.Problem{}))
+
+	for _, rt := range r.routes {
+		path, pathParams := convertPath(rt.path)
+		op := &Operation{
+			OperationID: rt.doc.OperationID,
+			Summary:     rt.doc.Summary,
+			Description: rt.doc.Description,
+			Tags:        rt.doc.Tags,
+			Deprecated:  rt.doc.Deprecated,
+			Responses: map[string]*OperationReply{
+				"200": {
+					Description: "success",
+					Content: map[string]*MediaType{
+						fiber.MIMEApplicationJSON: {Schema: envelope(g.schemaOf(rt.resp), &Schema{Type: "null"}, &Schema{Type: "integer"})},
+					},
+				},
+				"default": {
+					Description: "error",
+					Content: map[string]*MediaType{
+						fiber.MIMEApplicationJSON:           {Schema: &Schema{Ref: "#/components/schemas/ErrorResponse"}},
+						response.MIMEApplicationProblemJSON: {Schema: &Schema{Ref: "#/components/schemas/Problem"}},
+					},
+				},
+			},
+		}
+		for _, name := range pathParams {
+			op.Parameters = append(op.Parameters, &Parameter{Name: name, In: "path", Required: true, Schema: fieldSchema(g, rt.req, "params", name)})
+		}
+		switch rt.method {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodDelete:
+			op.Parameters = append(op.Parameters, queryParameters(g, rt.req)...)
+		default:
+			if hasFields(rt.req) {
+				op.RequestBody = &RequestBody{
+					Required: true,
+					Content: map[string]*MediaType{
+						fiber.MIMEApplicationJSON: {Schema: g.schemaOf(rt.req)},
+					},
+				}
+			}
+		}
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = map[string]*Operation{}
+		}
+		doc.Paths[path][strings.ToLower(rt.method)] = op
+	}
+	doc.Components.Schemas = g.components
+	return doc
+}
+
+// envelope schema of response.Response
+func envelope(data, errs, code *Schema) *Schema {
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"code":     code,
+			"data":     data,
+			"message":  {Type: "string"},
+			"language": {Type: "string"},
+			"errors":   errs,
+			"args":     {Type: "object", Description: "template args of the error message"},
+			"details": {Type: "array", Description: "structured error details, google.rpc error details in snake_case", Items: &Schema{
+				Type:       "object",
+				Properties: map[string]*Schema{"@type": {Type: "string"}},
+				Required:   []string{"@type"},
+			}},
+			"request_id": {Type: "string"},
+			"trace_id":   {Type: "string"},
+		},
+		Required: []string{"code", "data", "message", "language", "errors"},
+	}
+}
+
+func errorCatalogue() []ErrorCode {
+	var ret []ErrorCode
+	xerror.DefaultErrorMul.Range(func(key int, messages map[string]string) bool {
+		code := ErrorCode{Code: key, Messages: messages}
+		if def, ok := xerror.Lookup(key); ok {
+			code.Namespace, code.Name = def.Namespace, def.Name
+			code.HTTPStatus, code.Severity, code.Retryable = def.HTTPStatus, def.Severity.String(), def.Retryable
+		}
+		ret = append(ret, code)
+		return true
+	})
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Code < ret[j].Code
+	})
+	return ret
+}
+
+// convertPath convert fiber path "/users/:id" to "/users/{id}"
+func convertPath(path string) (string, []string) {
+	var (
+		params []string
+	)
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		if strings.HasPrefix(seg, ":") {
+			name := strings.TrimSuffix(strings.TrimPrefix(seg, ":"), "?")
+			params = append(params, name)
+			segments[i] = "{" + name + "}"
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+func structType(t reflect.Type) (reflect.Type, bool) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t, t.Kind() == reflect.Struct
+}
+
+func hasFields(t reflect.Type) bool {
+	st, ok := structType(t)
+	return !ok || st.NumField() > 0
+}
+
+// fieldSchema schema of the field tagged tag:"name", string when not found
+func fieldSchema(g *schemaGen, t reflect.Type, tag, name string) *Schema {
+	if st, ok := structType(t); ok {
+		for i := 0; i < st.NumField(); i++ {
+			f := st.Field(i)
+			if strings.Split(f.Tag.Get(tag), ",")[0] == name {
+				return g.schemaOf(f.Type)
+			}
+		}
+	}
+	return &Schema{Type: "string"}
+}
+
+func queryParameters(g *schemaGen, t reflect.Type) []*Parameter {
+	st, ok := structType(t)
+	if !ok {
+		return nil
+	}
+	var (
+		ret []*Parameter
+	)
+	for i := 0; i < st.NumField(); i++ {
+		f := st.Field(i)
+		name := strings.Split(f.Tag.Get("query"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		ret = append(ret, &Parameter{
+			Name:     name,
+			In:       "query",
+			Required: hasRule(strings.Split(f.Tag.Get("validate"), ","), "required"),
+			Schema:   g.schemaOf(f.Type),
+		})
+	}
+	return ret
+}
@@ -0,0 +1,143 @@
+package openapi
+
+import (
+	"encoding/json"
+	fiber "github.com/gofiber/fiber/v2"
+	"github.com/olongfen/toolkit/multi/xerror"
+	"github.com/olongfen/toolkit/response"
+	"github.com/olongfen/toolkit/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type createUser struct {
+	Name  string `json:"name" validate:"required" description:"user name"`
+	Email string `json:"email,omitempty"`
+	Role  string `json:"role" validate:"required,oneof=admin member"`
+	Level int    `json:"level,omitempty" validate:"omitempty,oneof=1 2"`
+}
+
+type getUser struct {
+	ID     int  `params:"id"`
+	Detail bool `query:"detail"`
+}
+
+type user struct {
+	tools.Model
+	Name  string          `json:"name"`
+	Tags  []string        `json:"tags"`
+	Extra map[string]int  `json:"extra"`
+	Birth *tools.JSONTime `json:"birth,omitempty"`
+	Boss  *user           `json:"boss,omitempty"`
+	Raw   json.RawMessage `json:"-"`
+}
+
+func TestHandle(t *testing.T) {
+	reg := NewRegistry("users", "1.0.0")
+	app := fiber.New(fiber.Config{ErrorHandler: response.ErrorHandler})
+	api := app.Group("/api")
+	Handle(reg, api, fiber.MethodPost, "/users", Route{Summary: "create user", Tags: []string{"user"}},
+		func(c *fiber.Ctx, req *createUser) (*user, error) {
+			return &user{Name: req.Name}, nil
+		})
+	Handle(reg, api, fiber.MethodGet, "/users/:id", Route{OperationID: "getUser"},
+		func(c *fiber.Ctx, req *getUser) (user, error) {
+			u := user{Name: "u" + strconv.Itoa(req.ID)}
+			if req.Detail {
+				u.Tags = []string{"detail"}
+			}
+			return u, nil
+		})
+	app.Get("/openapi.json", reg.Handler())
+
+	req := httptest.NewRequest(fiber.MethodPost, "/api/users", strings.NewReader(`{"name":"tom","role":"admin"}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	var ret response.TypedResponse[user]
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&ret))
+	assert.Equal(t, "tom", ret.Data.Name)
+
+	req = httptest.NewRequest(fiber.MethodPost, "/api/users", strings.NewReader(`{"role":"guest"}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	var failed response.Response
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&failed))
+	assert.Equal(t, xerror.IllegalParameter, failed.Code)
+	assert.Contains(t, failed.Errors, "name")
+	assert.Contains(t, failed.Errors, "role")
+	require.Len(t, failed.Details, 1)
+	bad := failed.Details[0].(xerror.BadRequest)
+	require.Len(t, bad.FieldViolations, 2)
+	assert.Equal(t, "name", bad.FieldViolations[0].Field)
+	assert.Equal(t, "required", bad.FieldViolations[0].Rule)
+	assert.Nil(t, bad.FieldViolations[0].Params)
+	assert.Equal(t, "role", bad.FieldViolations[1].Field)
+	assert.Equal(t, "oneof", bad.FieldViolations[1].Rule)
+	assert.Equal(t, xerror.Args{"oneof": "admin member"}, bad.FieldViolations[1].Params)
+
+	resp, err = app.Test(httptest.NewRequest(fiber.MethodGet, "/api/users/3?detail=true", nil))
+	require.NoError(t, err)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&ret))
+	assert.Equal(t, "u3", ret.Data.Name)
+	assert.Equal(t, []string{"detail"}, ret.Data.Tags)
+
+	resp, err = app.Test(httptest.NewRequest(fiber.MethodGet, "/openapi.json", nil))
+	require.NoError(t, err)
+	var doc Document
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&doc))
+	assert.Equal(t, Version, doc.OpenAPI)
+
+	create := doc.Paths["/api/users"]["post"]
+	require.NotNil(t, create)
+	body := create.RequestBody.Content[fiber.MIMEApplicationJSON].Schema
+	assert.Equal(t, "#/components/schemas/createUser", body.Ref)
+	schema := doc.Components.Schemas["createUser"]
+	assert.Equal(t, []string{"name", "role"}, schema.Required)
+	assert.Equal(t, []interface{}{"admin", "member"}, schema.Properties["role"].Enum)
+	// 枚举值与字段类型一致
+	assert.Equal(t, "integer", schema.Properties["level"].Type)
+	assert.Equal(t, []interface{}{float64(1), float64(2)}, schema.Properties["level"].Enum)
+	for kind, expect := range map[reflect.Kind]interface{}{reflect.Uint8: uint64(3), reflect.Float64: 3.0, reflect.String: "3"} {
+		v, ok := enumValue(kind, "3")
+		assert.True(t, ok)
+		assert.Equal(t, expect, v)
+	}
+	_, ok := enumValue(reflect.Int, "x")
+	assert.False(t, ok)
+	assert.Equal(t, "user name", schema.Properties["name"].Description)
+
+	data := create.Responses["200"].Content[fiber.MIMEApplicationJSON].Schema.Properties["data"]
+	assert.Equal(t, "#/components/schemas/user", data.Ref)
+	u := doc.Components.Schemas["user"]
+	assert.Contains(t, u.Properties, "ID")
+	assert.Equal(t, "date-time", u.Properties["birth"].Format)
+	assert.Equal(t, "#/components/schemas/user", u.Properties["boss"].Ref)
+	assert.NotContains(t, u.Properties, "Raw")
+
+	get := doc.Paths["/api/users/{id}"]["get"]
+	require.NotNil(t, get)
+	require.Len(t, get.Parameters, 2)
+	assert.Equal(t, "path", get.Parameters[0].In)
+	assert.Equal(t, "integer", get.Parameters[0].Schema.Type)
+	assert.Equal(t, "detail", get.Parameters[1].Name)
+
+	assert.Contains(t, doc.Components.Schemas, "ValidateError")
+	problem := doc.Components.Schemas["Problem"]
+	require.NotNil(t, problem)
+	assert.Empty(t, problem.Ref)
+	assert.Contains(t, problem.Properties, "status")
+	assert.Contains(t, problem.Properties, "detail")
+	assert.NotEmpty(t, doc.ErrorCatalogue)
+	assert.Equal(t, xerror.IllegalAccessToken, doc.ErrorCatalogue[0].Code)
+	assert.Equal(t, "Illegal token", doc.ErrorCatalogue[0].Messages["en"])
+	assert.Equal(t, "toolkit", doc.ErrorCatalogue[0].Namespace)
+	assert.Equal(t, "IllegalAccessToken", doc.ErrorCatalogue[0].Name)
+	assert.Equal(t, fiber.StatusUnauthorized, doc.ErrorCatalogue[0].HTTPStatus)
+}
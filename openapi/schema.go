@@ -0,0 +1,212 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema json schema of OpenAPI 3.1
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 interface{}        `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	invalidName   = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+)
+
+// schemaGen generate schemas, named struct types are put in components
+type schemaGen struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+func newSchemaGen() *schemaGen {
+	return &schemaGen{
+		components: map[string]*Schema{},
+		names:      map[reflect.Type]string{},
+	}
+}
+
+func (g *schemaGen) schemaOf(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if isTime(t) {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		zero := float64(0)
+		return &Schema{Type: "integer", Minimum: &zero}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if reflect.PointerTo(t).Implements(marshalerType) || t.Implements(marshalerType) {
+			// 自定义 json 格式无法推断
+			return &Schema{}
+		}
+		return g.structSchema(t)
+	}
+	return &Schema{}
+}
+
+func (g *schemaGen) structSchema(t reflect.Type) *Schema {
+	name := t.Name()
+	if name == "" || strings.Contains(name, "[") {
+		return g.objectSchema(t)
+	}
+	if ref, ok := g.names[t]; ok {
+		return &Schema{Ref: "#/components/schemas/" + ref}
+	}
+	ref := invalidName.ReplaceAllString(name, "_")
+	if _, exists := g.components[ref]; exists {
+		ref = invalidName.ReplaceAllString(t.PkgPath(), "_") + "." + ref
+	}
+	g.names[t] = ref
+	g.components[ref] = &Schema{}
+	*g.components[ref] = *g.objectSchema(t)
+	return &Schema{Ref: "#/components/schemas/" + ref}
+}
+
+func (g *schemaGen) objectSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	g.addFields(s, t)
+	return s
+}
+
+func (g *schemaGen) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, omitempty, skip := jsonName(f)
+		if skip {
+			continue
+		}
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && f.Tag.Get("json") == "" && ft.Kind() == reflect.Struct && !isTime(ft) {
+			g.addFields(s, ft)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		fs := g.schemaOf(f.Type)
+		if desc := f.Tag.Get("description"); desc != "" {
+			if fs.Ref != "" {
+				fs = &Schema{OneOf: []*Schema{fs}}
+			}
+			fs.Description = desc
+		}
+		rules := strings.Split(f.Tag.Get("validate"), ",")
+		for _, rule := range rules {
+			if strings.HasPrefix(rule, "oneof=") {
+				for _, v := range strings.Fields(strings.TrimPrefix(rule, "oneof=")) {
+					if ev, ok := enumValue(ft.Kind(), v); ok {
+						fs.Enum = append(fs.Enum, ev)
+					}
+				}
+			}
+		}
+		s.Properties[name] = fs
+		if hasRule(rules, "required") && !omitempty {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+func jsonName(f reflect.StructField) (name string, omitempty bool, skip bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	if name == "" {
+		name = f.Name
+	}
+	for _, p := range parts[1:] {
+		if p == "omitempty" {
+			omitempty = true
+		}
+	}
+	return
+}
+
+// enumValue oneof value v converted to kind, so enums of integer fields are integers
+func enumValue(kind reflect.Kind, v string) (interface{}, bool) {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(v, 10, 64)
+		return n, err == nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(v, 10, 64)
+		return n, err == nil
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(v, 64)
+		return n, err == nil
+	case reflect.Bool:
+		b, err := strconv.ParseBool(v)
+		return b, err == nil
+	}
+	return v, true
+}
+
+func hasRule(rules []string, rule string) bool {
+	for _, v := range rules {
+		if v == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// isTime time.Time and structs embedding it such as tools.JSONTime
+func isTime(t reflect.Type) bool {
+	if t == timeType {
+		return true
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); f.Anonymous && f.Type == timeType {
+			return true
+		}
+	}
+	return t.PkgPath() == "gorm.io/gorm" && t.Name() == "DeletedAt"
+}
@@ -0,0 +1,177 @@
+package response
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	fiber "github.com/gofiber/fiber/v2"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/olongfen/toolkit/multi/xerror"
+	"github.com/olongfen/toolkit/tools"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type cacheOptions struct {
+	etag         bool
+	lastModified time.Time
+	cacheControl string
+}
+
+// WithETag answer the weak ETagOf the data, If-None-Match is answered with 304
+func (r *Response) WithETag() *Response {
+	r.cache.etag = true
+	return r
+}
+
+// WithLastModified answer Last-Modified, If-Modified-Since is answered with 304
+func (r *Response) WithLastModified(t time.Time) *Response {
+	r.cache.lastModified = t
+	return r
+}
+
+// WithCacheControl answer Cache-Control policy, e.g. "private, max-age=60"
+func (r *Response) WithCacheControl(policy string) *Response {
+	r.cache.cacheControl = policy
+	return r
+}
+
+// LastModified latest UpdatedAt of models
+func LastModified(models ...tools.Model) time.Time {
+	var ret time.Time
+	for _, m := range models {
+		if m.UpdatedAt.After(ret) {
+			ret = m.UpdatedAt
+		}
+	}
+	return ret
+}
+
+// ETagOf weak ETag of data, the sha1 of its json form, the same value WithETag answers for it.
+// It is weak because the message, the language and the negotiated encoding of the body are left out,
+// use StrongETag for If-Match
+func ETagOf(data interface{}) (string, error) {
+	b, err := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	sum := sha1.Sum(b)
+	return `W/"` + hex.EncodeToString(sum[:]) + `"`, nil
+}
+
+// StrongETag strong validator of a version the caller controls, e.g. a version column or tools.Model.UpdatedAt,
+// checked by CheckIfMatch. Clients send it back in If-Match, answer it with the data, it is never the ETag of WithETag
+func StrongETag(version interface{}) string {
+	var s string
+	switch v := version.(type) {
+	case time.Time:
+		s = strconv.FormatInt(v.UnixNano(), 10)
+	default:
+		s = fmt.Sprint(v)
+	}
+	if !etagChars(s) {
+		sum := sha1.Sum([]byte(s))
+		s = hex.EncodeToString(sum[:])
+	}
+	return `"` + s + `"`
+}
+
+// etagChars s is a non-empty etagc sequence (RFC 7232 2.3)
+func etagChars(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c != 0x21 && (c < 0x23 || c > 0x7e) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckIfMatch check If-Match of update requests against etag, the StrongETag of the current version, by strong comparison,
+// a mismatch or a weak tag returns a localized xerror.PreconditionFailed answered with 412
+func CheckIfMatch(ctx *fiber.Ctx, etag string) error {
+	ifMatch := ctx.Get(fiber.HeaderIfMatch)
+	if ifMatch == "" {
+		return nil
+	}
+	if !etagMatch(ifMatch, etag, false) {
+		return xerror.NewErrorContext(ctx.UserContext(), xerror.PreconditionFailed)
+	}
+	return nil
+}
+
+// CacheControl route middleware setting Cache-Control of successful responses
+func CacheControl(policy string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		err := c.Next()
+		status := c.Response().StatusCode()
+		if err == nil && (status < http.StatusMultipleChoices || status == http.StatusNotModified) &&
+			len(c.Response().Header.Peek(fiber.HeaderCacheControl)) == 0 {
+			c.Set(fiber.HeaderCacheControl, policy)
+		}
+		return err
+	}
+}
+
+// conditional set validators and answer 304 when the request conditions match
+func (r *Response) conditional(ctx *fiber.Ctx) (bool, error) {
+	if r.cache.cacheControl != "" {
+		ctx.Set(fiber.HeaderCacheControl, r.cache.cacheControl)
+	}
+	if !r.cache.etag && r.cache.lastModified.IsZero() {
+		return false, nil
+	}
+	if m := ctx.Method(); m != fiber.MethodGet && m != fiber.MethodHead {
+		return false, nil
+	}
+	var etag string
+	if r.cache.etag {
+		var err error
+		if etag, err = ETagOf(r.Data); err != nil {
+			return false, err
+		}
+		ctx.Set(fiber.HeaderETag, etag)
+	}
+	if !r.cache.lastModified.IsZero() {
+		ctx.Set(fiber.HeaderLastModified, r.cache.lastModified.UTC().Format(http.TimeFormat))
+	}
+	notModified := false
+	if inm := ctx.Get(fiber.HeaderIfNoneMatch); inm != "" {
+		// If-None-Match 优先于 If-Modified-Since
+		notModified = etag != "" && etagMatch(inm, etag, true)
+	} else if ims := ctx.Get(fiber.HeaderIfModifiedSince); ims != "" && !r.cache.lastModified.IsZero() {
+		if t, err := http.ParseTime(ims); err == nil {
+			notModified = !r.cache.lastModified.Truncate(time.Second).After(t)
+		}
+	}
+	if !notModified {
+		return false, nil
+	}
+	ctx.Status(http.StatusNotModified)
+	ctx.Context().ResetBody()
+	return true, nil
+}
+
+// etagMatch compare header list against etag, weak comparison for If-None-Match,
+// strong comparison for If-Match where weak tags never match (RFC 7232 2.3.2)
+func etagMatch(header, etag string, weak bool) bool {
+	if weak {
+		etag = strings.TrimPrefix(etag, "W/")
+	} else if strings.HasPrefix(etag, "W/") {
+		return false
+	}
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimSpace(v)
+		if weak {
+			v = strings.TrimPrefix(v, "W/")
+		}
+		if v == "*" || v == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package response
+
+import (
+	"encoding/json"
+	fiber "github.com/gofiber/fiber/v2"
+	"github.com/olongfen/toolkit/multi/xerror"
+	"github.com/olongfen/toolkit/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newCacheApp(updated time.Time) *fiber.App {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	data := []item{{ID: 1, Name: "a"}}
+	app.Get("/items", CacheControl("private, max-age=60"), func(c *fiber.Ctx) error {
+		return NewResponse().WithETag().Success(c, data)
+	})
+	app.Get("/item", func(c *fiber.Ctx) error {
+		return NewResponse().WithLastModified(LastModified(tools.Model{UpdatedAt: updated})).
+			WithCacheControl("no-cache").Success(c, data[0])
+	})
+	app.Put("/item", func(c *fiber.Ctx) error {
+		if err := CheckIfMatch(c, StrongETag(updated)); err != nil {
+			return err
+		}
+		return NewResponse().Success(c, nil)
+	})
+	return app
+}
+
+func doRequest(t *testing.T, app *fiber.App, method, target string, header map[string]string) (*http.Response, []byte) {
+	req := httptest.NewRequest(method, target, nil)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	b, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, b
+}
+
+func TestETag(t *testing.T) {
+	app := newCacheApp(time.Now())
+	resp, _ := doRequest(t, app, fiber.MethodGet, "/items", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	etag := resp.Header.Get(fiber.HeaderETag)
+	assert.Regexp(t, `^W/"[0-9a-f]{40}"$`, etag)
+	assert.Equal(t, "private, max-age=60", resp.Header.Get(fiber.HeaderCacheControl))
+	expect, err := ETagOf([]item{{ID: 1, Name: "a"}})
+	require.NoError(t, err)
+	assert.Equal(t, expect, etag)
+
+	resp, b := doRequest(t, app, fiber.MethodGet, "/items", map[string]string{fiber.HeaderIfNoneMatch: etag})
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+	assert.Empty(t, b)
+	assert.Equal(t, etag, resp.Header.Get(fiber.HeaderETag))
+	assert.Equal(t, "private, max-age=60", resp.Header.Get(fiber.HeaderCacheControl))
+
+	resp, _ = doRequest(t, app, fiber.MethodGet, "/items", map[string]string{fiber.HeaderIfNoneMatch: etag[2:]})
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+
+	resp, _ = doRequest(t, app, fiber.MethodGet, "/items", map[string]string{fiber.HeaderIfNoneMatch: `"other"`})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestLastModified(t *testing.T) {
+	updated := time.Date(2023, 3, 1, 8, 0, 0, 500, time.UTC)
+	app := newCacheApp(updated)
+	resp, _ := doRequest(t, app, fiber.MethodGet, "/item", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, updated.Format(http.TimeFormat), resp.Header.Get(fiber.HeaderLastModified))
+	assert.Equal(t, "no-cache", resp.Header.Get(fiber.HeaderCacheControl))
+
+	resp, _ = doRequest(t, app, fiber.MethodGet, "/item", map[string]string{fiber.HeaderIfModifiedSince: updated.Format(http.TimeFormat)})
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+
+	resp, _ = doRequest(t, app, fiber.MethodGet, "/item", map[string]string{fiber.HeaderIfModifiedSince: updated.Add(-time.Hour).Format(http.TimeFormat)})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestCheckIfMatch(t *testing.T) {
+	updated := time.Date(2023, 3, 1, 8, 0, 0, 500, time.UTC)
+	app := newCacheApp(updated)
+	etag := StrongETag(updated)
+	assert.Equal(t, `"1677657600000000500"`, etag)
+
+	resp, _ := doRequest(t, app, fiber.MethodPut, "/item", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, _ = doRequest(t, app, fiber.MethodPut, "/item", map[string]string{fiber.HeaderIfMatch: etag})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, _ = doRequest(t, app, fiber.MethodPut, "/item", map[string]string{fiber.HeaderIfMatch: `"stale", ` + etag})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, _ = doRequest(t, app, fiber.MethodPut, "/item", map[string]string{fiber.HeaderIfMatch: "W/" + etag})
+	assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+
+	// WithETag 的弱 ETag 不能用于 If-Match
+	weak, _ := doRequest(t, app, fiber.MethodGet, "/items", nil)
+	resp, _ = doRequest(t, app, fiber.MethodPut, "/item", map[string]string{fiber.HeaderIfMatch: weak.Header.Get(fiber.HeaderETag)})
+	assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+
+	resp, b := doRequest(t, app, fiber.MethodPut, "/item", map[string]string{fiber.HeaderIfMatch: `"stale"`})
+	assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+	var ret Response
+	require.NoError(t, json.Unmarshal(b, &ret))
+	assert.Equal(t, xerror.PreconditionFailed, ret.Code)
+}
+
+func TestStrongETag(t *testing.T) {
+	assert.Equal(t, `"3"`, StrongETag(3))
+	assert.Equal(t, `"v1.2"`, StrongETag("v1.2"))
+	// 含有 etag 不允许的字符时取 sha1
+	assert.Regexp(t, `^"[0-9a-f]{40}"$`, StrongETag(`a "b"`))
+}
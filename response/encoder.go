@@ -0,0 +1,312 @@
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	fiber "github.com/gofiber/fiber/v2"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/vmihailenco/msgpack/v5"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// MIMEApplicationMsgpack msgpack content type
+	MIMEApplicationMsgpack = "application/msgpack"
+	// MIMEApplicationProblemXML problem details content type in xml
+	MIMEApplicationProblemXML = "application/problem+xml"
+
+	encoderLocalsKey = "__response_encoder"
+)
+
+// Encoder response body encoder
+type Encoder interface {
+	// ContentType content type written in header
+	ContentType() string
+	Encode(v interface{}) ([]byte, error)
+}
+
+var (
+	encoderMu sync.RWMutex
+	// encoders registered encoders, the first one is used when nothing matches Accept
+	encoders = []Encoder{
+		jsonEncoder{},
+		xmlEncoder{},
+		msgpackEncoder{contentType: MIMEApplicationMsgpack},
+		msgpackEncoder{contentType: "application/x-msgpack"},
+	}
+)
+
+// RegisterEncoder register encoder, an encoder of the same content type is replaced
+func RegisterEncoder(enc Encoder) {
+	encoderMu.Lock()
+	defer encoderMu.Unlock()
+	for i, v := range encoders {
+		if v.ContentType() == enc.ContentType() {
+			encoders[i] = enc
+			return
+		}
+	}
+	encoders = append(encoders, enc)
+}
+
+// EncoderFor get encoder of content type
+func EncoderFor(contentType string) (Encoder, bool) {
+	encoderMu.RLock()
+	defer encoderMu.RUnlock()
+	for _, v := range encoders {
+		if v.ContentType() == contentType {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// UseEncoder route middleware forcing the encoder of content type regardless of Accept
+func UseEncoder(contentType string) fiber.Handler {
+	if _, ok := EncoderFor(contentType); !ok {
+		panic("response: no encoder registered for " + contentType)
+	}
+	return func(c *fiber.Ctx) error {
+		c.Locals(encoderLocalsKey, contentType)
+		return c.Next()
+	}
+}
+
+// Negotiate choose encoder by route override then Accept header
+func Negotiate(ctx *fiber.Ctx) Encoder {
+	if ct, ok := ctx.Locals(encoderLocalsKey).(string); ok {
+		if enc, ok := EncoderFor(ct); ok {
+			return enc
+		}
+	}
+	encoderMu.RLock()
+	defer encoderMu.RUnlock()
+	accept := ctx.Get(fiber.HeaderAccept)
+	if accept == "" {
+		return encoders[0]
+	}
+	for _, spec := range parseAccept(accept) {
+		for _, enc := range encoders {
+			if mimeMatch(spec, enc.ContentType()) {
+				return enc
+			}
+		}
+	}
+	return encoders[0]
+}
+
+// send encode v by negotiated encoder, contentType overrides the encoder content type,
+// json is encoded by the fiber.Config.JSONEncoder of the app
+func send(ctx *fiber.Ctx, status int, v interface{}, contentType func(enc Encoder) string) error {
+	var (
+		enc = Negotiate(ctx)
+		b   []byte
+		err error
+	)
+	if _, ok := enc.(jsonEncoder); ok && ctx.App().Config().JSONEncoder != nil {
+		b, err = ctx.App().Config().JSONEncoder(v)
+	} else {
+		b, err = enc.Encode(v)
+	}
+	if err != nil {
+		return err
+	}
+	ct := enc.ContentType()
+	if contentType != nil {
+		ct = contentType(enc)
+	}
+	ctx.Vary(fiber.HeaderAccept)
+	ctx.Status(status)
+	ctx.Set(fiber.HeaderContentType, ct)
+	return ctx.Send(b)
+}
+
+// parseAccept media ranges of Accept ordered by q, q=0 ranges are dropped
+func parseAccept(accept string) []string {
+	type mediaRange struct {
+		mime string
+		q    float64
+	}
+	var (
+		ranges []mediaRange
+	)
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		r := mediaRange{mime: strings.ToLower(strings.TrimSpace(params[0])), q: 1}
+		for _, p := range params[1:] {
+			p = strings.TrimSpace(p)
+			if strings.HasPrefix(p, "q=") {
+				if q, err := strconv.ParseFloat(p[2:], 64); err == nil {
+					r.q = q
+				}
+			}
+		}
+		if r.mime != "" && r.q > 0 {
+			ranges = append(ranges, r)
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+	ret := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		ret = append(ret, r.mime)
+	}
+	return ret
+}
+
+func mimeMatch(spec, mime string) bool {
+	if spec == "*/*" || spec == mime {
+		return true
+	}
+	if strings.HasSuffix(spec, "/*") {
+		return strings.HasPrefix(mime, spec[:len(spec)-1])
+	}
+	return false
+}
+
+// jsonEncoder jsoniter, responses are encoded by the JSONEncoder of the app instead
+type jsonEncoder struct{}
+
+func (jsonEncoder) ContentType() string {
+	return fiber.MIMEApplicationJSON
+}
+
+func (jsonEncoder) Encode(v interface{}) ([]byte, error) {
+	return jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(v)
+}
+
+type msgpackEncoder struct {
+	contentType string
+}
+
+func (e msgpackEncoder) ContentType() string {
+	return e.contentType
+}
+
+func (msgpackEncoder) Encode(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// xmlEncoder encode the json form of value as xml, so maps and json tags work as in json,
+// values and Response.Data implementing xml.Marshaler are encoded by encoding/xml instead
+type xmlEncoder struct{}
+
+func (xmlEncoder) ContentType() string {
+	return fiber.MIMEApplicationXML
+}
+
+func (xmlEncoder) Encode(v interface{}) ([]byte, error) {
+	var (
+		tree interface{}
+		buf  bytes.Buffer
+		data xml.Marshaler
+	)
+	if m, ok := v.(xml.Marshaler); ok {
+		return xml.Marshal(m)
+	}
+	if r, ok := v.(*Response); ok {
+		if m, ok := r.Data.(xml.Marshaler); ok {
+			cp := *r
+			cp.Data, v, data = nil, &cp, m
+		}
+	}
+	b, err := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err = dec.Decode(&tree); err != nil {
+		return nil, err
+	}
+	if data != nil {
+		tree.(map[string]interface{})["data"] = data
+	}
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	if err = encodeXMLNode(enc, xml.StartElement{Name: xml.Name{Local: "response"}}, tree); err != nil {
+		return nil, err
+	}
+	if err = enc.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeXMLNode(enc *xml.Encoder, start xml.StartElement, node interface{}) error {
+	if node == nil {
+		return nil
+	}
+	if m, ok := node.(xml.Marshaler); ok {
+		return enc.EncodeElement(m, start)
+	}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	switch val := node.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			child := xml.StartElement{Name: xml.Name{Local: k}}
+			if !validXMLName(k) {
+				child = xml.StartElement{
+					Name: xml.Name{Local: "entry"},
+					Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: k}},
+				}
+			}
+			if err := encodeXMLNode(enc, child, val[k]); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, item := range val {
+			if err := encodeXMLNode(enc, xml.StartElement{Name: xml.Name{Local: "item"}}, item); err != nil {
+				return err
+			}
+		}
+	default:
+		var s string
+		switch v := val.(type) {
+		case string:
+			s = v
+		case bool:
+			s = strconv.FormatBool(v)
+		case json.Number:
+			s = v.String()
+		}
+		if err := enc.EncodeToken(xml.CharData(s)); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+func validXMLName(s string) bool {
+	if s == "" || strings.HasPrefix(strings.ToLower(s), "xml") {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z':
+		case i > 0 && (r == '-' || r == '.' || r >= '0' && r <= '9'):
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,116 @@
+package response
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	fiber "github.com/gofiber/fiber/v2"
+	"github.com/olongfen/toolkit/multi/xerror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+	"io"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+type item struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func newEncoderApp() *fiber.App {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Get("/items", func(c *fiber.Ctx) error {
+		return NewResponse().Success(c, []item{{ID: 1, Name: "a&b"}})
+	})
+	app.Get("/legacy", UseEncoder(fiber.MIMEApplicationXML), func(c *fiber.Ctx) error {
+		return NewResponse().Success(c, map[string]int{"count": 2})
+	})
+	app.Get("/fail", func(c *fiber.Ctx) error {
+		return xerror.ValidateError{"name": "required"}
+	})
+	return app
+}
+
+func get(t *testing.T, app *fiber.App, target, accept string) (string, []byte) {
+	req := httptest.NewRequest(fiber.MethodGet, target, nil)
+	if accept != "" {
+		req.Header.Set(fiber.HeaderAccept, accept)
+	}
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	b, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.Header.Get(fiber.HeaderContentType), b
+}
+
+func TestNegotiate(t *testing.T) {
+	app := newEncoderApp()
+
+	ct, b := get(t, app, "/items", "")
+	assert.Equal(t, fiber.MIMEApplicationJSON, ct)
+	var ret Response
+	require.NoError(t, json.Unmarshal(b, &ret))
+	assert.Equal(t, "成功", ret.Message)
+
+	ct, b = get(t, app, "/items", "application/json;q=0.5, application/msgpack")
+	assert.Equal(t, MIMEApplicationMsgpack, ct)
+	var m map[string]interface{}
+	require.NoError(t, msgpack.Unmarshal(b, &m))
+	assert.Equal(t, "a&b", m["data"].([]interface{})[0].(map[string]interface{})["name"])
+
+	ct, b = get(t, app, "/items", "text/html, application/*;q=0.8")
+	assert.Equal(t, fiber.MIMEApplicationJSON, ct)
+
+	ct, b = get(t, app, "/items", "application/xml")
+	assert.Equal(t, fiber.MIMEApplicationXML, ct)
+	assert.Contains(t, string(b), "<data><item><id>1</id><name>a&amp;b</name></item></data>")
+
+	ct, b = get(t, app, "/legacy", "application/json")
+	assert.Equal(t, fiber.MIMEApplicationXML, ct)
+	assert.Contains(t, string(b), "<count>2</count>")
+
+	ct, b = get(t, app, "/fail", "application/xml")
+	assert.Equal(t, fiber.MIMEApplicationXML, ct)
+	assert.Contains(t, string(b), "<errors><name>required</name></errors>")
+}
+
+func TestNegotiateProblem(t *testing.T) {
+	DefaultErrorMode = ProblemMode
+	defer func() {
+		DefaultErrorMode = EnvelopeMode
+	}()
+	ct, b := get(t, newEncoderApp(), "/fail", "application/xml")
+	assert.Equal(t, MIMEApplicationProblemXML, ct)
+	assert.Contains(t, string(b), "<status>400</status>")
+}
+
+type point struct {
+	X, Y int
+}
+
+func (p point) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "x"}, Value: strconv.Itoa(p.X)}, xml.Attr{Name: xml.Name{Local: "y"}, Value: strconv.Itoa(p.Y)})
+	return e.EncodeElement("", start)
+}
+
+func TestNegotiateAppEncoder(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler, JSONEncoder: func(v interface{}) ([]byte, error) {
+		return []byte(`{"encoder":"app"}`), nil
+	}})
+	app.Get("/", func(c *fiber.Ctx) error {
+		return NewResponse().Success(c, point{X: 1, Y: 2})
+	})
+	app.Get("/fail", func(c *fiber.Ctx) error {
+		return xerror.ValidateError{"name": "required"}
+	})
+
+	_, b := get(t, app, "/", "")
+	assert.Equal(t, `{"encoder":"app"}`, string(b))
+	_, b = get(t, app, "/fail", "")
+	assert.Equal(t, `{"encoder":"app"}`, string(b))
+
+	_, b = get(t, app, "/", "application/xml")
+	assert.Contains(t, string(b), `<data x="1" y="2"></data>`)
+}
@@ -0,0 +1,306 @@
+package response
+
+import (
+	"context"
+	"errors"
+	fiber "github.com/gofiber/fiber/v2"
+	"github.com/olongfen/toolkit/multi/xerror"
+	"github.com/olongfen/toolkit/scontext"
+	"github.com/olongfen/toolkit/xlog"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+)
+
+// ErrorResult result of mapping an error
+type ErrorResult struct {
+	// Status http status in problem mode
+	Status int
+	// EnvelopeStatus http status in envelope mode, default EnvelopeStatusOf Code, 200 when not registered
+	EnvelopeStatus int
+	Code           int
+	// Message localized xerror.MessageFailed when empty
+	Message string
+	// Errors {"error": err.Error()} when nil
+	Errors interface{}
+	// Args template args of the biz error message
+	Args xerror.Args
+	// Details structured details of the error
+	Details xerror.Details
+	// Unknown no mapper matched the error
+	Unknown bool
+}
+
+// ErrorMapper map err to result, return false to pass err to the next mapper
+type ErrorMapper func(ctx *fiber.Ctx, err error) (*ErrorResult, bool)
+
+// ErrorHook called with every handled error, used for logging and metrics
+type ErrorHook func(ctx *fiber.Ctx, err error, result *ErrorResult)
+
+// ErrorHandlerOption error handler option
+type ErrorHandlerOption func(h *errorHandler)
+
+type errorHandler struct {
+	mode             *ErrorMode
+	production       bool
+	envelopeStatuses bool
+	mappers          []ErrorMapper
+	hooks            []ErrorHook
+}
+
+// WithMode render errors in mode, default DefaultErrorMode
+func WithMode(mode ErrorMode) ErrorHandlerOption {
+	return func(h *errorHandler) {
+		h.mode = &mode
+	}
+}
+
+// WithProduction hide messages of unknown errors from clients
+func WithProduction(production bool) ErrorHandlerOption {
+	return func(h *errorHandler) {
+		h.production = production
+	}
+}
+
+// WithEnvelopeStatuses answer StatusOf the code in envelope mode, e.g. 404 for xerror.RecordNotFound,
+// by default biz errors answer 200 except the codes of RegisterStatus
+func WithEnvelopeStatuses() ErrorHandlerOption {
+	return func(h *errorHandler) {
+		h.envelopeStatuses = true
+	}
+}
+
+// WithMapper register mapper, mappers run in registration order before the built-in ones
+func WithMapper(mapper ErrorMapper) ErrorHandlerOption {
+	return func(h *errorHandler) {
+		h.mappers = append(h.mappers, mapper)
+	}
+}
+
+// MapAs register mapper for errors matching target type T by errors.As
+func MapAs[T error](fn func(ctx *fiber.Ctx, err T) *ErrorResult) ErrorHandlerOption {
+	return WithMapper(func(ctx *fiber.Ctx, err error) (*ErrorResult, bool) {
+		var target T
+		if !errors.As(err, &target) {
+			return nil, false
+		}
+		return fn(ctx, target), true
+	})
+}
+
+// WithHook register hook
+func WithHook(hook ErrorHook) ErrorHandlerOption {
+	return func(h *errorHandler) {
+		h.hooks = append(h.hooks, hook)
+	}
+}
+
+// WithMetrics register hook receiving http status and code of every handled error
+func WithMetrics(fn func(ctx *fiber.Ctx, status int, code int)) ErrorHandlerOption {
+	return WithHook(func(ctx *fiber.Ctx, err error, result *ErrorResult) {
+		fn(ctx, result.Status, result.Code)
+	})
+}
+
+// WithLogger log unknown errors at error level and biz errors with stack at warn level,
+// biz error messages are logged in xlog.OperatorLanguage
+func WithLogger(log *zap.Logger) ErrorHandlerOption {
+	return WithHook(func(ctx *fiber.Ctx, err error, result *ErrorResult) {
+		fields := []zap.Field{
+			zap.String("method", ctx.Method()),
+			zap.String("path", ctx.Path()),
+			zap.Int("status", result.Status),
+			zap.Int("code", result.Code),
+		}
+		if result.Unknown {
+			log.Error("Internal Error", append(fields, zap.Error(err))...)
+			return
+		}
+		if e, ok := xerror.FromError(err); ok && e.StackError() != nil {
+			log.Warn("Business Error", append(fields, xlog.Error(e))...)
+		}
+	})
+}
+
+// NewErrorHandler new fiber error handler
+func NewErrorHandler(opts ...ErrorHandlerOption) fiber.ErrorHandler {
+	h := &errorHandler{}
+	for _, opt := range opts {
+		opt(h)
+	}
+	h.mappers = append(h.mappers, func(ctx *fiber.Ctx, err error) (*ErrorResult, bool) {
+		return h.builtinMapper(ctx.UserContext(), err)
+	})
+	return h.handle
+}
+
+func (h *errorHandler) handle(ctx *fiber.Ctx, err error) error {
+	result := h.mapError(ctx, err)
+	for _, hook := range h.hooks {
+		hook(ctx, err, result)
+	}
+	mode := DefaultErrorMode
+	if h.mode != nil {
+		mode = *h.mode
+	}
+	if mode == ProblemMode {
+		return writeProblem(ctx, result)
+	}
+	resp := NewResponse()
+	resp.Code = result.Code
+	resp.Message = result.Message
+	resp.Errors = result.Errors
+	resp.Args = result.Args
+	resp.Details = result.Details
+	resp.RequestID, resp.TraceID = correlate(ctx)
+	return send(ctx, result.EnvelopeStatus, resp, nil)
+}
+
+func (h *errorHandler) mapError(ctx *fiber.Ctx, err error) *ErrorResult {
+	var (
+		result *ErrorResult
+		ok     bool
+	)
+	for _, mapper := range h.mappers {
+		if result, ok = mapper(ctx, err); ok {
+			break
+		}
+	}
+	if !ok || result == nil {
+		result = &ErrorResult{
+			Status:         http.StatusInternalServerError,
+			EnvelopeStatus: http.StatusInternalServerError,
+			Code:           xerror.Failed,
+			Unknown:        true,
+		}
+	}
+	if result.Status == 0 {
+		result.Status = ProblemStatusOf(result.Code)
+	}
+	if result.EnvelopeStatus == 0 {
+		result.EnvelopeStatus = http.StatusOK
+		if status, ok := h.envelopeStatusOf(result.Code); ok && !result.Unknown {
+			result.EnvelopeStatus = status
+		}
+	}
+	if result.Message == "" {
+		result.Message = xerror.DefaultErrorMul.Message(xerror.MessageFailed, scontext.GetLanguage(ctx.UserContext()))
+	}
+	if result.Errors == nil {
+		if result.Unknown && h.production {
+			result.Errors = map[string]any{"error": http.StatusText(result.Status)}
+		} else {
+			result.Errors = map[string]any{"error": err.Error()}
+		}
+	}
+	return result
+}
+
+func (h *errorHandler) envelopeStatusOf(code int) (int, bool) {
+	if h.envelopeStatuses {
+		return StatusOf(code)
+	}
+	return EnvelopeStatusOf(code)
+}
+
+// builtinMapper map the outermost fiber, biz, validate or db error in err chain,
+// every error contained in a multi-error is rendered in the errors map
+func (h *errorHandler) builtinMapper(ctx context.Context, err error) (*ErrorResult, bool) {
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if multi, ok := e.(interface{ Unwrap() []error }); ok {
+			return h.multiErrorResult(ctx, multi.Unwrap())
+		}
+		if result, ok := knownErrorResult(ctx, e); ok {
+			return result, true
+		}
+	}
+	return nil, false
+}
+
+func (h *errorHandler) multiErrorResult(ctx context.Context, errs []error) (*ErrorResult, bool) {
+	var (
+		result *ErrorResult
+		m      = map[string]string{}
+	)
+	for i, e := range errs {
+		if e == nil {
+			continue
+		}
+		sub, ok := h.builtinMapper(ctx, e)
+		if !ok {
+			sub = &ErrorResult{Status: http.StatusInternalServerError, EnvelopeStatus: http.StatusInternalServerError, Code: xerror.Failed, Unknown: true}
+		}
+		if result == nil {
+			result = &ErrorResult{Status: sub.Status, EnvelopeStatus: sub.EnvelopeStatus, Code: sub.Code, Message: sub.Message, Unknown: sub.Unknown}
+		}
+		result.Details = append(result.Details, sub.Details...)
+		switch v := sub.Errors.(type) {
+		case map[string]string:
+			for k, msg := range v {
+				m[k] = msg
+			}
+		case xerror.ValidateError:
+			for k, msg := range v {
+				m[k] = msg
+			}
+		default:
+			msg := e.Error()
+			if sub.Unknown && h.production {
+				msg = http.StatusText(http.StatusInternalServerError)
+			} else if sub.Message != "" {
+				msg = sub.Message
+			}
+			m[strconv.Itoa(i)] = msg
+		}
+	}
+	if result == nil {
+		return nil, false
+	}
+	result.Errors = m
+	return result, true
+}
+
+func knownErrorResult(ctx context.Context, err error) (*ErrorResult, bool) {
+	switch e := err.(type) {
+	case *fiber.Error:
+		// 处理内部错误返回
+		return &ErrorResult{Status: e.Code, Code: e.Code}, true
+	case xerror.BizError:
+		// 处理自定义业务错误返回, 按客户端语言渲染
+		return &ErrorResult{Code: e.Code(), Message: e.Localize(scontext.GetLanguage(ctx)), Args: e.Args(), Details: e.Details()}, true
+	case xerror.FieldViolations:
+		return &ErrorResult{
+			Code:    xerror.IllegalParameter,
+			Message: xerror.NewErrorContext(ctx, xerror.IllegalParameter).Error(),
+			Errors:  e.ValidateError(),
+			Details: xerror.Details{e.BadRequest()},
+		}, true
+	case xerror.ValidateError:
+		return &ErrorResult{
+			Code:    xerror.IllegalParameter,
+			Message: xerror.NewErrorContext(ctx, xerror.IllegalParameter).Error(),
+			Errors:  e,
+			Details: xerror.Details{e.BadRequest()},
+		}, true
+	case xerror.DBErrorResponse:
+		// 处理数据库错误返回, code 取排序后第一个字段的错误码, 与 xgrpc 一致
+		var (
+			m      = map[string]string{}
+			result = &ErrorResult{Code: xerror.Failed}
+			lan    = scontext.GetLanguage(ctx)
+		)
+		for _, k := range e.Fields() {
+			if result.Code == xerror.Failed {
+				result.Code = e[k].Code()
+			}
+			m[k] = e[k].Localize(lan)
+			result.Details = append(result.Details, e[k].Details()...)
+		}
+		if len(e) > 0 {
+			result.Details = append(xerror.Details{e.BadRequest(lan)}, result.Details...)
+		}
+		result.Errors = m
+		return result, true
+	}
+	return nil, false
+}
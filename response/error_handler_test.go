@@ -0,0 +1,209 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	fiber "github.com/gofiber/fiber/v2"
+	"github.com/olongfen/toolkit/consts"
+	"github.com/olongfen/toolkit/multi/xerror"
+	"github.com/olongfen/toolkit/scontext"
+	"github.com/olongfen/toolkit/xlog/xlogtest"
+	pkgerrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"net/http/httptest"
+	"testing"
+)
+
+type quotaError struct {
+	limit int
+}
+
+func (e *quotaError) Error() string {
+	return "quota exceeded"
+}
+
+// joinError errors.Join of go 1.20, go.mod is still go 1.19
+type joinError []error
+
+func (e joinError) Error() string {
+	return "joined"
+}
+
+func (e joinError) Unwrap() []error {
+	return e
+}
+
+func TestNewErrorHandler(t *testing.T) {
+	var (
+		statuses []int
+	)
+	handler := NewErrorHandler(
+		WithProduction(true),
+		MapAs(func(ctx *fiber.Ctx, err *quotaError) *ErrorResult {
+			return &ErrorResult{Status: fiber.StatusTooManyRequests, EnvelopeStatus: fiber.StatusTooManyRequests, Code: 42900, Errors: map[string]int{"limit": err.limit}}
+		}),
+		WithMetrics(func(ctx *fiber.Ctx, status int, code int) {
+			statuses = append(statuses, status)
+		}),
+	)
+	do := func(err error) (int, map[string]interface{}) {
+		app := fiber.New(fiber.Config{ErrorHandler: handler})
+		app.Get("/", func(c *fiber.Ctx) error {
+			return err
+		})
+		resp, e := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+		require.NoError(t, e)
+		var ret map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&ret))
+		return resp.StatusCode, ret
+	}
+
+	status, ret := do(&quotaError{limit: 5})
+	assert.Equal(t, fiber.StatusTooManyRequests, status)
+	assert.Equal(t, float64(42900), ret["code"])
+	assert.Equal(t, map[string]interface{}{"limit": float64(5)}, ret["errors"])
+
+	status, ret = do(errors.New("password=secret"))
+	assert.Equal(t, fiber.StatusInternalServerError, status)
+	assert.Equal(t, map[string]interface{}{"error": "Internal Server Error"}, ret["errors"])
+	assert.Equal(t, []int{fiber.StatusTooManyRequests, fiber.StatusInternalServerError}, statuses)
+
+	status, ret = do(fiber.ErrNotFound)
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, float64(fiber.StatusNotFound), ret["code"])
+	assert.Equal(t, "失败", ret["message"])
+}
+
+func TestErrorHandlerLogger(t *testing.T) {
+	log, logs := xlogtest.NewLogger()
+	app := fiber.New(fiber.Config{ErrorHandler: NewErrorHandler(WithLogger(log))})
+	app.Get("/", func(c *fiber.Ctx) error {
+		return errors.New("boom")
+	})
+	_, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+	require.NoError(t, err)
+	xlogtest.AssertLogged(t, logs, zapcore.ErrorLevel, zap.String("path", "/"), zap.Int("code", -1))
+}
+
+func TestErrorHandlerWrapped(t *testing.T) {
+	do := func(err error) (int, Response) {
+		app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+		app.Get("/", func(c *fiber.Ctx) error {
+			c.SetUserContext(scontext.SetLanguage(c.UserContext(), consts.English))
+			return err
+		})
+		resp, e := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+		require.NoError(t, e)
+		var ret Response
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&ret))
+		return resp.StatusCode, ret
+	}
+
+	notFound := xerror.NewError(xerror.RecordNotFound, consts.English).WithError(fiber.ErrNotFound)
+	status, ret := do(fmt.Errorf("get user: %w", pkgerrors.WithStack(notFound)))
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, xerror.RecordNotFound, ret.Code)
+	assert.Equal(t, "record not found", ret.Message)
+
+	status, ret = do(joinError{
+		xerror.ValidateError{"name": "name is required"},
+		xerror.DBErrorResponse{"email": xerror.NewError(xerror.AlreadyExists, consts.English)},
+		notFound,
+	})
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, xerror.IllegalParameter, ret.Code)
+	assert.Equal(t, map[string]interface{}{
+		"name":  "name is required",
+		"email": "already exists,duplicate creation is not allowed",
+		"2":     "record not found",
+	}, ret.Errors)
+}
+
+func TestErrorArgs(t *testing.T) {
+	xerror.DefaultErrorMul.Set(40098, consts.English, "field '{{.Field}}' already exists")
+	defer xerror.DefaultErrorMul.DeleteKey(40098)
+	for _, mode := range []ErrorMode{EnvelopeMode, ProblemMode} {
+		app := fiber.New(fiber.Config{ErrorHandler: NewErrorHandler(WithMode(mode))})
+		app.Get("/", func(c *fiber.Ctx) error {
+			return xerror.NewErrorf(40098, consts.English, xerror.Args{"Field": "email"})
+		})
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+		require.NoError(t, err)
+		var ret map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&ret))
+		assert.Equal(t, map[string]interface{}{"Field": "email"}, ret["args"])
+		if mode == ProblemMode {
+			assert.Equal(t, "field 'email' already exists", ret["detail"])
+		} else {
+			assert.Equal(t, "field 'email' already exists", ret["message"])
+		}
+	}
+}
+
+func TestErrorHandlerLanguage(t *testing.T) {
+	log, logs := xlogtest.NewLogger()
+	app := fiber.New(fiber.Config{ErrorHandler: NewErrorHandler(WithLogger(log))})
+	app.Get("/", func(c *fiber.Ctx) error {
+		c.SetUserContext(scontext.SetLanguage(c.UserContext(), consts.TraditionalChinese))
+		return xerror.NewError(xerror.RecordNotFound, consts.SimplifiedChinese).WithError(errors.New("sql: no rows"))
+	})
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+	require.NoError(t, err)
+	var ret Response
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&ret))
+	// 响应使用客户端语言, 日志使用 xlog.OperatorLanguage
+	assert.Equal(t, "記錄未找到", ret.Message)
+	entries := logs.FilterMessage("Business Error").All()
+	require.Len(t, entries, 1)
+	logged := entries[0].ContextMap()["error"].(map[string]interface{})
+	assert.Equal(t, xerror.RecordNotFound, logged["code"])
+	assert.Equal(t, "record not found", logged["message"])
+	assert.Equal(t, "sql: no rows", logged["cause"])
+	assert.Contains(t, logged["stack"].([]interface{})[0], "response.TestErrorHandlerLanguage")
+}
+
+func TestErrorDetails(t *testing.T) {
+	for _, mode := range []ErrorMode{EnvelopeMode, ProblemMode} {
+		app := fiber.New(fiber.Config{ErrorHandler: NewErrorHandler(WithMode(mode))})
+		app.Get("/quota", func(c *fiber.Ctx) error {
+			return xerror.NewError(xerror.Forbidden, consts.English).WithDetails(xerror.QuotaFailure{
+				Violations: []xerror.QuotaViolation{{Subject: "user:1001", Description: "daily limit"}},
+			})
+		})
+		app.Get("/validate", func(c *fiber.Ctx) error {
+			return xerror.ValidateError{"name": "name is required"}
+		})
+		app.Get("/db", func(c *fiber.Ctx) error {
+			return xerror.DBErrorResponse{
+				"email": xerror.NewError(xerror.AlreadyExists, consts.English).WithDetails(xerror.ResourceInfo{ResourceType: "user", ResourceName: "users/1"}),
+			}
+		})
+		do := func(path string) interface{} {
+			resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, path, nil))
+			require.NoError(t, err)
+			var ret map[string]interface{}
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&ret))
+			return ret["details"]
+		}
+		assert.Equal(t, []interface{}{map[string]interface{}{
+			"@type":      xerror.TypeQuotaFailure,
+			"violations": []interface{}{map[string]interface{}{"subject": "user:1001", "description": "daily limit"}},
+		}}, do("/quota"))
+		assert.Equal(t, []interface{}{map[string]interface{}{
+			"@type":            xerror.TypeBadRequest,
+			"field_violations": []interface{}{map[string]interface{}{"field": "name", "description": "name is required"}},
+		}}, do("/validate"))
+		// 数据库错误按客户端语言渲染, 字段错误的详情附在 BadRequest 之后
+		assert.Equal(t, []interface{}{
+			map[string]interface{}{
+				"@type":            xerror.TypeBadRequest,
+				"field_violations": []interface{}{map[string]interface{}{"field": "email", "description": "已经存在,不允许重复创建"}},
+			},
+			map[string]interface{}{"@type": xerror.TypeResourceInfo, "resource_type": "user", "resource_name": "users/1"},
+		}, do("/db"))
+	}
+}
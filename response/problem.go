@@ -0,0 +1,95 @@
+package response
+
+import (
+	fiber "github.com/gofiber/fiber/v2"
+	"github.com/olongfen/toolkit/multi/xerror"
+	"github.com/olongfen/toolkit/scontext"
+	"net/http"
+	"strconv"
+)
+
+// ErrorMode how ErrorHandler renders errors
+type ErrorMode int
+
+const (
+	// EnvelopeMode {code,data,message,language,errors} envelope, biz errors answer 200 except the codes of RegisterStatus,
+	// WithEnvelopeStatuses answers StatusOf every registered code
+	EnvelopeMode ErrorMode = iota
+	// ProblemMode RFC 7807 application/problem+json with real http status
+	ProblemMode
+)
+
+// MIMEApplicationProblemJSON problem details content type
+const MIMEApplicationProblemJSON = "application/problem+json"
+
+var (
+	// DefaultErrorMode mode used by ErrorHandler
+	DefaultErrorMode = EnvelopeMode
+	// ProblemTypeBase problem type is ProblemTypeBase + biz code, "about:blank" when empty
+	ProblemTypeBase = ""
+)
+
+// Problem RFC 7807 problem details
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// 扩展字段
+	Code      int            `json:"code"`
+	Language  string         `json:"language,omitempty"`
+	Errors    interface{}    `json:"errors,omitempty"`
+	Args      xerror.Args    `json:"args,omitempty"`
+	Details   xerror.Details `json:"details,omitempty"`
+	RequestID string         `json:"request_id,omitempty"`
+	TraceID   string         `json:"trace_id,omitempty"`
+}
+
+// ProblemStatusOf http status of biz error code in problem mode, StatusOf the code, unknown codes answer 400
+func ProblemStatusOf(code int) int {
+	if status, ok := StatusOf(code); ok {
+		return status
+	}
+	return http.StatusBadRequest
+}
+
+// NewProblem new problem of status
+func NewProblem(status int, code int, detail string) *Problem {
+	p := &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+	if ProblemTypeBase != "" {
+		p.Type = ProblemTypeBase + strconv.Itoa(code)
+	}
+	return p
+}
+
+// Send write problem as application/problem+json, or problem+xml and other negotiated encodings
+func (p *Problem) Send(ctx *fiber.Ctx) error {
+	return send(ctx, p.Status, p, func(enc Encoder) string {
+		switch enc.ContentType() {
+		case fiber.MIMEApplicationJSON:
+			return MIMEApplicationProblemJSON
+		case fiber.MIMEApplicationXML:
+			return MIMEApplicationProblemXML
+		}
+		return enc.ContentType()
+	})
+}
+
+func writeProblem(ctx *fiber.Ctx, result *ErrorResult) error {
+	userCtx := ctx.UserContext()
+	p := NewProblem(result.Status, result.Code, result.Message)
+	p.Instance = ctx.OriginalURL()
+	p.Language = scontext.GetLanguage(userCtx)
+	p.Errors = result.Errors
+	p.Args = result.Args
+	p.Details = result.Details
+	p.RequestID, p.TraceID = correlate(ctx)
+	return p.Send(ctx)
+}
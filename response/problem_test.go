@@ -0,0 +1,94 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	fiber "github.com/gofiber/fiber/v2"
+	"github.com/olongfen/toolkit/consts"
+	"github.com/olongfen/toolkit/multi/xerror"
+	"github.com/olongfen/toolkit/scontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http/httptest"
+	"testing"
+)
+
+func newErrorApp(err error) *fiber.App {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Get("/items/:id", func(c *fiber.Ctx) error {
+		c.SetUserContext(scontext.SetLanguage(c.UserContext(), consts.English))
+		return err
+	})
+	return app
+}
+
+func TestProblemMode(t *testing.T) {
+	DefaultErrorMode = ProblemMode
+	ProblemTypeBase = "https://errors.example.com/"
+	defer func() {
+		DefaultErrorMode = EnvelopeMode
+		ProblemTypeBase = ""
+	}()
+
+	cases := []struct {
+		err    error
+		status int
+		code   int
+	}{
+		{xerror.NewError(xerror.RecordNotFound, consts.English), fiber.StatusNotFound, xerror.RecordNotFound},
+		{xerror.ValidateError{"name": "name is required"}, fiber.StatusBadRequest, xerror.IllegalParameter},
+		{xerror.DBErrorResponse{"name": xerror.NewError(xerror.AlreadyExists, consts.English)}, fiber.StatusConflict, xerror.AlreadyExists},
+		{fiber.ErrMethodNotAllowed, fiber.StatusMethodNotAllowed, fiber.StatusMethodNotAllowed},
+		{errors.New("boom"), fiber.StatusInternalServerError, -1},
+	}
+	for _, v := range cases {
+		resp, err := newErrorApp(v.err).Test(httptest.NewRequest(fiber.MethodGet, "/items/1?x=1", nil))
+		require.NoError(t, err)
+		assert.Equal(t, v.status, resp.StatusCode)
+		assert.Equal(t, MIMEApplicationProblemJSON, resp.Header.Get(fiber.HeaderContentType))
+		var p Problem
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&p))
+		assert.Equal(t, v.status, p.Status)
+		assert.Equal(t, v.code, p.Code)
+		assert.Equal(t, "/items/1?x=1", p.Instance)
+		assert.Equal(t, consts.English, p.Language)
+		assert.NotEmpty(t, p.Title)
+	}
+
+	resp, err := newErrorApp(xerror.NewError(xerror.RecordNotFound, consts.English)).Test(httptest.NewRequest(fiber.MethodGet, "/items/1", nil))
+	require.NoError(t, err)
+	var p Problem
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&p))
+	assert.Equal(t, "https://errors.example.com/40004", p.Type)
+	assert.Equal(t, "record not found", p.Detail)
+}
+
+func TestEnvelopeMode(t *testing.T) {
+	resp, err := newErrorApp(xerror.NewError(xerror.RecordNotFound, consts.English)).Test(httptest.NewRequest(fiber.MethodGet, "/items/1", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var ret Response
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&ret))
+	assert.Equal(t, xerror.RecordNotFound, ret.Code)
+	assert.Equal(t, "record not found", ret.Message)
+}
+
+func TestEnvelopeStatuses(t *testing.T) {
+	do := func(err error, opts ...ErrorHandlerOption) int {
+		app := fiber.New(fiber.Config{ErrorHandler: NewErrorHandler(opts...)})
+		app.Get("/", func(c *fiber.Ctx) error {
+			return err
+		})
+		resp, e := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+		require.NoError(t, e)
+		return resp.StatusCode
+	}
+	// 默认只有 RegisterStatus 的 code 不回答 200
+	assert.Equal(t, fiber.StatusOK, do(xerror.NewError(xerror.AlreadyExists, consts.English)))
+	assert.Equal(t, fiber.StatusForbidden, do(xerror.NewError(xerror.Forbidden, consts.English)))
+	assert.Equal(t, fiber.StatusPreconditionFailed, do(xerror.NewError(xerror.PreconditionFailed, consts.English)))
+
+	assert.Equal(t, fiber.StatusConflict, do(xerror.NewError(xerror.AlreadyExists, consts.English), WithEnvelopeStatuses()))
+	assert.Equal(t, fiber.StatusBadRequest, do(xerror.ValidateError{"name": "required"}, WithEnvelopeStatuses()))
+	assert.Equal(t, fiber.StatusInternalServerError, do(errors.New("boom"), WithEnvelopeStatuses()))
+}
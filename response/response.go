@@ -6,17 +6,65 @@ import (
 	"github.com/olongfen/toolkit/multi/xerror"
 	"github.com/olongfen/toolkit/scontext"
 	"net/http"
+	"sync"
 )
 
+const messageLocalsKey = "__response_message"
+
+var (
+	statusMu sync.RWMutex
+	// envelopeStatus 信封模式下不回答 200 的 code
+	envelopeStatus = map[int]int{
+		xerror.IllegalAccessToken: http.StatusUnauthorized,
+		xerror.IllegalCertificate: http.StatusUnauthorized,
+		xerror.Forbidden:          http.StatusForbidden,
+		xerror.PreconditionFailed: http.StatusPreconditionFailed,
+	}
+)
+
+// RegisterStatus register http status of biz error code in envelope mode,
+// other biz errors answer 200 unless WithEnvelopeStatuses
+func RegisterStatus(code int, status int) {
+	statusMu.Lock()
+	envelopeStatus[code] = status
+	statusMu.Unlock()
+}
+
+// EnvelopeStatusOf http status of biz error code in envelope mode registered by RegisterStatus
+func EnvelopeStatusOf(code int) (int, bool) {
+	statusMu.RLock()
+	defer statusMu.RUnlock()
+	status, ok := envelopeStatus[code]
+	return status, ok
+}
+
+// StatusOf http status of biz error code, the HTTPStatus of its xerror.Definition,
+// used in problem mode and in envelope mode WithEnvelopeStatuses
+func StatusOf(code int) (int, bool) {
+	if def, ok := xerror.Lookup(code); ok && def.HTTPStatus != 0 {
+		return def.HTTPStatus, true
+	}
+	return 0, false
+}
+
 // Response http response
 type Response struct {
-	status int
+	status    int
+	cache     cacheOptions
+	messageID xerror.MessageID
 	//
 	Code     int         `json:"code"`
 	Data     interface{} `json:"data"`
 	Message  string      `json:"message"`
 	Language string      `json:"language"`
 	Errors   interface{} `json:"errors"`
+	// Args 业务错误消息的模板参数
+	Args xerror.Args `json:"args,omitempty"`
+	// Details 结构化的错误详情
+	Details xerror.Details `json:"details,omitempty"`
+	// 关联日志的请求 id 与链路 id
+	RequestID string `json:"request_id,omitempty"`
+	TraceID   string `json:"trace_id,omitempty"`
 }
 
 // NewResponse new
@@ -36,97 +84,55 @@ func (r *Response) SetMessage(msg string) *Response {
 	return r
 }
 
+// SetMessageID set id of the localized envelope message in xerror.DefaultErrorMul, e.g. xerror.MessageCreated
+func (r *Response) SetMessageID(id xerror.MessageID) *Response {
+	r.messageID = id
+	return r
+}
+
+// UseMessage route middleware setting the default message id of Success, SetMessageID overrides it
+func UseMessage(id xerror.MessageID) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Locals(messageLocalsKey, id)
+		return c.Next()
+	}
+}
+
 // Success response success
 func (r *Response) Success(ctx *fiber.Ctx, data interface{}) error {
 	r.Data = data
 	userCtx := ctx.UserContext()
 	lan := scontext.GetLanguage(userCtx)
 	if r.Message == "" {
-		switch lan {
-		case consts.English:
-			r.Message = "success"
-		case consts.TraditionalChinese:
-			r.Message = "成功"
-		default:
-			r.Message = "成功"
+		id := xerror.MessageSuccess
+		if r.messageID != "" {
+			id = r.messageID
+		} else if v, ok := ctx.Locals(messageLocalsKey).(xerror.MessageID); ok {
+			id = v
 		}
-
+		r.Message = xerror.DefaultErrorMul.Message(id, lan)
 	}
-	r.Language = scontext.GetLanguage(ctx.UserContext())
-	return ctx.Status(r.status).JSON(r)
+	r.Language = lan
+	r.RequestID, r.TraceID = correlate(ctx)
+	if notModified, err := r.conditional(ctx); err != nil || notModified {
+		return err
+	}
+	return send(ctx, r.status, r, nil)
 }
 
-// ErrorHandler fiber error handler
-var ErrorHandler = func(ctx *fiber.Ctx, err error) error {
-	status := fiber.StatusOK
+// correlate get request id and trace id of ctx and set them to response headers
+func correlate(ctx *fiber.Ctx) (requestID, traceID string) {
 	userCtx := ctx.UserContext()
-	lan := scontext.GetLanguage(userCtx)
-	resp := NewResponse()
-	switch lan {
-	case consts.English:
-		resp.Message = "failed"
-	case consts.TraditionalChinese:
-		resp.Message = "失敗"
-	default:
-		resp.Message = "失败"
+	requestID = scontext.GetRequestID(userCtx)
+	traceID = scontext.GetTraceID(userCtx)
+	if requestID != "" {
+		ctx.Set(consts.HeaderRequestID, requestID)
 	}
-	resp.Code = -1
-	switch err.(type) {
-	case *fiber.Error:
-		// 处理内部错误返回
-		e := err.(*fiber.Error)
-		resp.Code = e.Code
-	case xerror.BizError:
-		// 处理自定义业务错误返回
-		e := err.(xerror.BizError)
-		resp.Code = e.Code()
-		resp.Message = e.Error()
-	case xerror.ValidateError:
-		resp.SetErrors(err.(xerror.ValidateError))
-		resp.Code = xerror.IllegalParameter
-		resp.Message = xerror.NewError(xerror.IllegalParameter, lan).Error()
-	case xerror.DBErrorResponse:
-		// 处理数据库错误返回
-		var (
-			m = map[string]string{}
-		)
-		e := err.(xerror.DBErrorResponse)
-		for k, v := range e {
-			resp.Code = v.Code()
-			m[k] = v.Error()
-		}
-		resp.SetErrors(m)
-	default:
-		status = fiber.StatusInternalServerError
+	if traceID != "" {
+		ctx.Set(consts.HeaderTraceID, traceID)
 	}
-	/*	// 处理内部错误返回
-		if e, ok := err.(*fiber.Error); ok {
-			xlog.Log.Error("HTTP Error", zap.Error(err))
-			resp.Code = e.Code
-			resp.Message = "failed"
-		}
-		// 处理自定义业务错误返回
-		if e, ok := err.(err_mul.BizError); ok {
-			xlog.Log.Error("Business Error", zap.Error(e.StackError()))
-			resp.Code = e.Code()
-			resp.Message = e.Error()
-		}
-		// 处理数据库错误返回
-		if e, ok := err.(err_mul.DBErrorResponse); ok {
-			var (
-				m = map[string]string{}
-			)
-			for k, v := range e {
-				resp.Code = v.Code()
-				m[k] = v.Error()
-			}
-			resp.Message = "failed"
-			resp.SetErrors(m)
-		}*/
-
-	if resp.Errors == nil {
-		resp.Errors = map[string]any{"error": err.Error()}
-	}
-
-	return ctx.Status(status).JSON(resp)
+	return
 }
+
+// ErrorHandler fiber error handler, the default configuration of NewErrorHandler
+var ErrorHandler = NewErrorHandler()
@@ -0,0 +1,58 @@
+package response
+
+import (
+	"encoding/json"
+	fiber "github.com/gofiber/fiber/v2"
+	"github.com/olongfen/toolkit/multi/xerror"
+	"github.com/olongfen/toolkit/scontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+)
+
+func TestSuccessMessage(t *testing.T) {
+	// 在副本上添加 ja, 结束后换回原来的 bundle
+	defer xerror.DefaultErrorMul.Swap(xerror.DefaultErrorMul.Swap(xerror.DefaultErrorMul.Bundle().Clone()))
+	xerror.DefaultErrorMul.SetMessage(xerror.MessageSuccess, "ja", "成功しました")
+	xerror.DefaultErrorMul.SetMessage(xerror.MessageFailed, "ja", "失敗しました")
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Use(func(c *fiber.Ctx) error {
+		if lan := c.Query("lang"); lan != "" {
+			c.SetUserContext(scontext.SetLanguage(c.UserContext(), lan))
+		}
+		return c.Next()
+	})
+	app.Get("/", func(c *fiber.Ctx) error {
+		return NewResponse().Success(c, nil)
+	})
+	app.Post("/", UseMessage(xerror.MessageCreated), func(c *fiber.Ctx) error {
+		return NewResponse().Success(c, nil)
+	})
+	app.Delete("/", UseMessage(xerror.MessageCreated), func(c *fiber.Ctx) error {
+		return NewTypedResponse[int]().SetMessageID(xerror.MessageDeleted).Success(c, 1)
+	})
+	app.Get("/fail", func(c *fiber.Ctx) error {
+		return fiber.ErrTeapot
+	})
+
+	cases := []struct {
+		method, target, message string
+	}{
+		{fiber.MethodGet, "/", "成功"},
+		{fiber.MethodGet, "/?lang=en", "success"},
+		{fiber.MethodGet, "/?lang=ja", "成功しました"},
+		{fiber.MethodGet, "/?lang=fr", "成功"},
+		{fiber.MethodPost, "/?lang=en", "created"},
+		{fiber.MethodDelete, "/?lang=zh-tw", "刪除成功"},
+		{fiber.MethodGet, "/fail?lang=ja", "失敗しました"},
+		{fiber.MethodGet, "/fail?lang=en", "failed"},
+	}
+	for _, c := range cases {
+		resp, b := doRequest(t, app, c.method, c.target, nil)
+		assert.Contains(t, []int{http.StatusOK, http.StatusTeapot}, resp.StatusCode)
+		var ret Response
+		require.NoError(t, json.Unmarshal(b, &ret))
+		assert.Equal(t, c.message, ret.Message, c.target)
+	}
+}
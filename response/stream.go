@@ -0,0 +1,244 @@
+package response
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	fiber "github.com/gofiber/fiber/v2"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/olongfen/toolkit/multi/xerror"
+	"github.com/olongfen/toolkit/scontext"
+	"gorm.io/gorm"
+	"mime"
+)
+
+var (
+	// ErrStreamTx RowsCursor of a transaction
+	ErrStreamTx = errors.New("response: rows cursor can not stream a transaction, the transaction ends before the body is written")
+)
+
+const (
+	// MIMEApplicationNDJSON newline delimited json content type
+	MIMEApplicationNDJSON = "application/x-ndjson"
+	// MIMETextEventStream server-sent events content type
+	MIMETextEventStream = "text/event-stream"
+	// MIMETextCSV csv content type
+	MIMETextCSV = "text/csv; charset=utf-8"
+)
+
+// Cursor iterate items, yield returning an error stops the iteration
+type Cursor[T any] func(yield func(item T) error) error
+
+// RowsCursor cursor over db.Rows(), every row is scanned into T by db.ScanRows.
+// The query runs in the body stream writer after the handler returned, so db must not be bound to a transaction
+// or to a context canceled by the handler, a transaction fails with ErrStreamTx
+func RowsCursor[T any](db *gorm.DB) Cursor[T] {
+	return func(yield func(item T) error) error {
+		if _, ok := db.Statement.ConnPool.(gorm.TxCommitter); ok {
+			return ErrStreamTx
+		}
+		rows, err := db.Rows()
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var item T
+			if err = db.ScanRows(rows, &item); err != nil {
+				return err
+			}
+			if err = yield(item); err != nil {
+				return err
+			}
+		}
+		return rows.Err()
+	}
+}
+
+// SliceCursor cursor over items
+func SliceCursor[T any](items []T) Cursor[T] {
+	return func(yield func(item T) error) error {
+		for _, item := range items {
+			if err := yield(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// StreamConfig stream config
+type StreamConfig struct {
+	// FlushEvery flush after every n items, default 100
+	FlushEvery int
+	// BOM write utf-8 bom before csv so excel detects the encoding
+	BOM bool
+}
+
+// StreamError error written in-band when the iteration fails after the response started,
+// mapped like the error handler, unknown errors are always hidden behind xerror.MessageFailed
+type StreamError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func newStreamConfig(configs []StreamConfig) StreamConfig {
+	var conf StreamConfig
+	if len(configs) > 0 {
+		conf = configs[0]
+	}
+	if conf.FlushEvery <= 0 {
+		conf.FlushEvery = 100
+	}
+	return conf
+}
+
+// newStreamError map err by the built-in mapping of the error handler in the language of userCtx
+func newStreamError(userCtx context.Context, err error) *StreamError {
+	var (
+		h          = &errorHandler{production: true}
+		result, ok = h.builtinMapper(userCtx, err)
+		failed     = xerror.DefaultErrorMul.Message(xerror.MessageFailed, scontext.GetLanguage(userCtx))
+	)
+	if !ok || result.Unknown {
+		return &StreamError{Code: xerror.Failed, Message: failed}
+	}
+	if result.Message == "" {
+		result.Message = failed
+	}
+	return &StreamError{Code: result.Code, Message: result.Message}
+}
+
+// stream run cursor in the body stream writer, the fiber ctx must not be used inside write
+func stream[T any](ctx *fiber.Ctx, conf StreamConfig, cursor Cursor[T], begin func(w *bufio.Writer) error,
+	write func(w *bufio.Writer, item T) error, end func(w *bufio.Writer, e *StreamError)) {
+	// 写 body 时 handler 已返回, fiber ctx 已被回收
+	userCtx := ctx.UserContext()
+	ctx.Set(fiber.HeaderCacheControl, "no-cache")
+	ctx.Set("X-Accel-Buffering", "no")
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if begin != nil {
+			if err := begin(w); err != nil {
+				return
+			}
+		}
+		var n int
+		err := cursor(func(item T) error {
+			if err := write(w, item); err != nil {
+				return err
+			}
+			n++
+			if n%conf.FlushEvery == 0 {
+				return w.Flush()
+			}
+			return nil
+		})
+		if err != nil {
+			end(w, newStreamError(userCtx, err))
+		} else {
+			end(w, nil)
+		}
+		_ = w.Flush()
+	})
+}
+
+// NDJSON stream items as newline delimited json, a failure is written as a final {"error":{...}} line
+func NDJSON[T any](ctx *fiber.Ctx, cursor Cursor[T], config ...StreamConfig) error {
+	conf := newStreamConfig(config)
+	ctx.Set(fiber.HeaderContentType, MIMEApplicationNDJSON)
+	stream(ctx, conf, cursor, nil, func(w *bufio.Writer, item T) error {
+		b, err := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(item)
+		if err != nil {
+			return err
+		}
+		_, _ = w.Write(b)
+		return w.WriteByte('\n')
+	}, func(w *bufio.Writer, e *StreamError) {
+		if e == nil {
+			return
+		}
+		b, _ := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(map[string]*StreamError{"error": e})
+		_, _ = w.Write(b)
+		_ = w.WriteByte('\n')
+	})
+	return nil
+}
+
+// SSE stream items as server-sent "message" events, followed by an "end" event or an "error" event
+func SSE[T any](ctx *fiber.Ctx, cursor Cursor[T], config ...StreamConfig) error {
+	conf := newStreamConfig(config)
+	ctx.Set(fiber.HeaderContentType, MIMETextEventStream)
+	ctx.Set(fiber.HeaderConnection, "keep-alive")
+	var id int
+	event := func(w *bufio.Writer, name string, v interface{}) error {
+		b, err := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(v)
+		if err != nil {
+			return err
+		}
+		id++
+		_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", id, name, b)
+		return err
+	}
+	stream(ctx, conf, cursor, nil, func(w *bufio.Writer, item T) error {
+		return event(w, "message", item)
+	}, func(w *bufio.Writer, e *StreamError) {
+		if e != nil {
+			_ = event(w, "error", e)
+			return
+		}
+		_ = event(w, "end", map[string]int{"count": id})
+	})
+	return nil
+}
+
+// CSVColumn csv column
+type CSVColumn[T any] struct {
+	// Key header used when Header has no entry of the request language
+	Key string
+	// Header localized headers, language -> header
+	Header map[string]string
+	Value  func(item T) string
+}
+
+// CSV stream items as csv attachment, a failure is written as a final "#error" record
+func CSV[T any](ctx *fiber.Ctx, filename string, columns []CSVColumn[T], cursor Cursor[T], config ...StreamConfig) error {
+	conf := newStreamConfig(config)
+	lang := scontext.GetLanguage(ctx.UserContext())
+	ctx.Set(fiber.HeaderContentType, MIMETextCSV)
+	ctx.Set(fiber.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	var cw *csv.Writer
+	stream(ctx, conf, cursor, func(w *bufio.Writer) error {
+		if conf.BOM {
+			_, _ = w.WriteString("\ufeff")
+		}
+		cw = csv.NewWriter(w)
+		header := make([]string, 0, len(columns))
+		for _, col := range columns {
+			if h, ok := col.Header[lang]; ok {
+				header = append(header, h)
+			} else {
+				header = append(header, col.Key)
+			}
+		}
+		return cw.Write(header)
+	}, func(w *bufio.Writer, item T) error {
+		record := make([]string, 0, len(columns))
+		for _, col := range columns {
+			record = append(record, col.Value(item))
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+		// csv.Writer 自带缓冲,需要先刷到 w 才能按批次 flush
+		cw.Flush()
+		return cw.Error()
+	}, func(w *bufio.Writer, e *StreamError) {
+		if e != nil {
+			_ = cw.Write([]string{"#error", fmt.Sprint(e.Code), e.Message})
+		}
+		cw.Flush()
+	})
+	return nil
+}
@@ -0,0 +1,109 @@
+package response
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"github.com/glebarez/sqlite"
+	fiber "github.com/gofiber/fiber/v2"
+	"github.com/olongfen/toolkit/consts"
+	"github.com/olongfen/toolkit/multi/xerror"
+	"github.com/olongfen/toolkit/scontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func failingCursor(n int, err error) Cursor[item] {
+	return func(yield func(item item) error) error {
+		for i := 1; i <= n; i++ {
+			if e := yield(item{ID: i, Name: "n" + strconv.Itoa(i)}); e != nil {
+				return e
+			}
+		}
+		return err
+	}
+}
+
+func streamBody(t *testing.T, handler fiber.Handler) (string, string) {
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		c.SetUserContext(scontext.SetLanguage(c.UserContext(), consts.English))
+		return c.Next()
+	}, handler)
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+	require.NoError(t, err)
+	var b strings.Builder
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		b.WriteString(sc.Text() + "\n")
+	}
+	return resp.Header.Get(fiber.HeaderContentType), b.String()
+}
+
+func TestNDJSON(t *testing.T) {
+	ct, body := streamBody(t, func(c *fiber.Ctx) error {
+		return NDJSON(c, failingCursor(250, xerror.NewError(xerror.RecordNotFound, consts.English)), StreamConfig{FlushEvery: 10})
+	})
+	assert.Equal(t, MIMEApplicationNDJSON, ct)
+	lines := strings.Split(strings.TrimSpace(body), "\n")
+	require.Len(t, lines, 251)
+	var first item
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	assert.Equal(t, item{ID: 1, Name: "n1"}, first)
+	var last map[string]StreamError
+	require.NoError(t, json.Unmarshal([]byte(lines[250]), &last))
+	assert.Equal(t, StreamError{Code: xerror.RecordNotFound, Message: "record not found"}, last["error"])
+}
+
+func TestSSE(t *testing.T) {
+	ct, body := streamBody(t, func(c *fiber.Ctx) error {
+		return SSE(c, SliceCursor([]item{{ID: 1}, {ID: 2}}))
+	})
+	assert.Equal(t, MIMETextEventStream, ct)
+	assert.Contains(t, body, "id: 1\nevent: message\ndata: {\"id\":1,\"name\":\"\"}\n")
+	assert.Contains(t, body, "event: end\ndata: {\"count\":2}\n")
+
+	_, body = streamBody(t, func(c *fiber.Ctx) error {
+		return SSE(c, failingCursor(1, errors.New("password=secret")))
+	})
+	assert.Contains(t, body, "event: error\ndata: {\"code\":-1,\"message\":\"failed\"}\n")
+}
+
+func TestCSV(t *testing.T) {
+	columns := []CSVColumn[item]{
+		{Key: "id", Header: map[string]string{consts.English: "ID", consts.SimplifiedChinese: "编号"}, Value: func(v item) string { return strconv.Itoa(v.ID) }},
+		{Key: "name", Value: func(v item) string { return v.Name }},
+	}
+	ct, body := streamBody(t, func(c *fiber.Ctx) error {
+		return CSV(c, "items.csv", columns, failingCursor(2, errors.New("password=secret")), StreamConfig{BOM: true})
+	})
+	assert.Equal(t, MIMETextCSV, ct)
+	assert.Equal(t, "\ufeffID,name\n1,n1\n2,n2\n#error,-1,failed\n", body)
+}
+
+func TestRowsCursor(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "stream.db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&item{}))
+	require.NoError(t, db.Create([]item{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}}).Error)
+
+	_, body := streamBody(t, func(c *fiber.Ctx) error {
+		return NDJSON(c, RowsCursor[item](db.Model(&item{}).Order("id")))
+	})
+	assert.Equal(t, "{\"id\":1,\"name\":\"a\"}\n{\"id\":2,\"name\":\"b\"}\n", body)
+
+	var items []item
+	assert.ErrorIs(t, db.Transaction(func(tx *gorm.DB) error {
+		return RowsCursor[item](tx.Model(&item{}))(func(v item) error {
+			items = append(items, v)
+			return nil
+		})
+	}), ErrStreamTx)
+	assert.Empty(t, items)
+}
@@ -0,0 +1,50 @@
+package response
+
+import (
+	fiber "github.com/gofiber/fiber/v2"
+	"github.com/olongfen/toolkit/multi/xerror"
+)
+
+// TypedResponse http response with typed data, it has the same json form as Response
+type TypedResponse[T any] struct {
+	Code      int            `json:"code"`
+	Data      T              `json:"data"`
+	Message   string         `json:"message"`
+	Language  string         `json:"language"`
+	Errors    interface{}    `json:"errors"`
+	Args      xerror.Args    `json:"args,omitempty"`
+	Details   xerror.Details `json:"details,omitempty"`
+	RequestID string         `json:"request_id,omitempty"`
+	TraceID   string         `json:"trace_id,omitempty"`
+
+	messageID xerror.MessageID
+}
+
+// NewTypedResponse new
+func NewTypedResponse[T any]() *TypedResponse[T] {
+	return &TypedResponse[T]{}
+}
+
+// SetMessage set message
+func (r *TypedResponse[T]) SetMessage(msg string) *TypedResponse[T] {
+	r.Message = msg
+	return r
+}
+
+// SetMessageID set id of the localized envelope message, see Response.SetMessageID
+func (r *TypedResponse[T]) SetMessageID(id xerror.MessageID) *TypedResponse[T] {
+	r.messageID = id
+	return r
+}
+
+// Success response success
+func (r *TypedResponse[T]) Success(ctx *fiber.Ctx, data T) error {
+	resp := NewResponse().SetMessage(r.Message)
+	resp.messageID = r.messageID
+	return resp.Success(ctx, data)
+}
+
+// Success response data in the envelope
+func Success[T any](ctx *fiber.Ctx, data T) error {
+	return NewTypedResponse[T]().Success(ctx, data)
+}
@@ -3,15 +3,17 @@ package scontext
 import (
 	"context"
 	"github.com/olongfen/toolkit/consts"
+	"github.com/olongfen/toolkit/i18n"
+	"go.opentelemetry.io/otel/trace"
 	"strings"
 )
 
 type languageCtxTag struct {
 }
 
-// SetLanguage set language to context
+// SetLanguage set language normalized by i18n.Normalize to context
 func SetLanguage(ctx context.Context, lang string) context.Context {
-	return context.WithValue(ctx, languageCtxTag{}, lang)
+	return context.WithValue(ctx, languageCtxTag{}, i18n.Normalize(lang))
 }
 
 // GetLanguage get language by context
@@ -36,3 +38,41 @@ func GetUserUuid(ctx context.Context) string {
 	}
 	return ""
 }
+
+type claimsCtxTag struct{}
+
+// SetClaims set token claims to context
+func SetClaims(ctx context.Context, claims map[string]interface{}) context.Context {
+	return context.WithValue(ctx, claimsCtxTag{}, claims)
+}
+
+// GetClaims get token claims by context
+func GetClaims(ctx context.Context) map[string]interface{} {
+	if val, ok := ctx.Value(claimsCtxTag{}).(map[string]interface{}); ok {
+		return val
+	}
+	return nil
+}
+
+type requestIDCtxTag struct{}
+
+// SetRequestID set request id to context
+func SetRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDCtxTag{}, requestID)
+}
+
+// GetRequestID get request id by context
+func GetRequestID(ctx context.Context) string {
+	if val, ok := ctx.Value(requestIDCtxTag{}).(string); ok {
+		return val
+	}
+	return ""
+}
+
+// GetTraceID get trace id of the active otel span
+func GetTraceID(ctx context.Context) string {
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		return sc.TraceID().String()
+	}
+	return ""
+}
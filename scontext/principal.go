@@ -0,0 +1,157 @@
+package scontext
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// HeaderPrincipal header used to propagate principal to downstream services
+const HeaderPrincipal = "X-Principal"
+
+// ErrInvalidPrincipal principal header can not be decoded
+var ErrInvalidPrincipal = errors.New("scontext: invalid principal")
+
+// Principal request principal
+type Principal struct {
+	UserUuid  string
+	Tenant    string
+	Roles     []string
+	Scopes    []string
+	SessionID string
+	ClientIP  string
+	UserAgent string
+	RequestID string
+	// Deadline 请求剩余时间的截止时间,为零表示不限制
+	Deadline time.Time
+}
+
+// principalHeader compact form of principal, deadline is sent as remaining budget
+type principalHeader struct {
+	UserUuid  string   `json:"u,omitempty"`
+	Tenant    string   `json:"t,omitempty"`
+	Roles     []string `json:"r,omitempty"`
+	Scopes    []string `json:"s,omitempty"`
+	SessionID string   `json:"sid,omitempty"`
+	ClientIP  string   `json:"ip,omitempty"`
+	UserAgent string   `json:"ua,omitempty"`
+	RequestID string   `json:"rid,omitempty"`
+	Budget    int64    `json:"b,omitempty"` // 毫秒
+}
+
+// HasRole check principal has role
+func (p Principal) HasRole(role string) bool {
+	return contains(p.Roles, role)
+}
+
+// HasScope check principal has scope
+func (p Principal) HasScope(scope string) bool {
+	return contains(p.Scopes, scope)
+}
+
+// Budget remaining time before deadline, zero means no deadline
+func (p Principal) Budget() time.Duration {
+	if p.Deadline.IsZero() {
+		return 0
+	}
+	if d := time.Until(p.Deadline); d > 0 {
+		return d
+	}
+	return -1
+}
+
+// Clone deep copy principal
+func (p Principal) Clone() Principal {
+	p.Roles = append([]string(nil), p.Roles...)
+	p.Scopes = append([]string(nil), p.Scopes...)
+	return p
+}
+
+// Encode encode principal to compact header form
+func (p Principal) Encode() string {
+	h := principalHeader{
+		UserUuid:  p.UserUuid,
+		Tenant:    p.Tenant,
+		Roles:     p.Roles,
+		Scopes:    p.Scopes,
+		SessionID: p.SessionID,
+		ClientIP:  p.ClientIP,
+		UserAgent: p.UserAgent,
+		RequestID: p.RequestID,
+	}
+	if budget := p.Budget(); budget != 0 {
+		h.Budget = budget.Milliseconds()
+		if h.Budget == 0 {
+			h.Budget = -1
+		}
+	}
+	b, _ := json.Marshal(h)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodePrincipal decode principal from header form
+func DecodePrincipal(s string) (Principal, error) {
+	var (
+		h principalHeader
+		p Principal
+	)
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return p, ErrInvalidPrincipal
+	}
+	if err = json.Unmarshal(b, &h); err != nil {
+		return p, ErrInvalidPrincipal
+	}
+	p = Principal{
+		UserUuid:  h.UserUuid,
+		Tenant:    h.Tenant,
+		Roles:     h.Roles,
+		Scopes:    h.Scopes,
+		SessionID: h.SessionID,
+		ClientIP:  h.ClientIP,
+		UserAgent: h.UserAgent,
+		RequestID: h.RequestID,
+	}
+	if h.Budget != 0 {
+		p.Deadline = time.Now().Add(time.Duration(h.Budget) * time.Millisecond)
+	}
+	return p, nil
+}
+
+type principalCtxTag struct{}
+
+// SetPrincipal set principal to context, user uuid is also set for GetUserUuid
+func SetPrincipal(ctx context.Context, p Principal) context.Context {
+	ctx = context.WithValue(ctx, principalCtxTag{}, p.Clone())
+	if p.UserUuid != "" {
+		ctx = SetUserUuid(ctx, p.UserUuid)
+	}
+	return ctx
+}
+
+// GetPrincipal get principal by context
+func GetPrincipal(ctx context.Context) (Principal, bool) {
+	if val, ok := ctx.Value(principalCtxTag{}).(Principal); ok {
+		return val.Clone(), true
+	}
+	return Principal{}, false
+}
+
+// WithDeadline apply principal deadline budget to context
+func WithDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p, ok := GetPrincipal(ctx); ok && !p.Deadline.IsZero() {
+		return context.WithDeadline(ctx, p.Deadline)
+	}
+	return context.WithCancel(ctx)
+}
+
+func contains(arr []string, s string) bool {
+	for _, v := range arr {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
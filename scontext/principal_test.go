@@ -0,0 +1,46 @@
+package scontext
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestPrincipal(t *testing.T) {
+	p := Principal{
+		UserUuid:  "u1",
+		Tenant:    "t1",
+		Roles:     []string{"admin"},
+		Scopes:    []string{"project:read"},
+		SessionID: "s1",
+		ClientIP:  "127.0.0.1",
+		UserAgent: "curl/8.0",
+		RequestID: "r1",
+		Deadline:  time.Now().Add(time.Minute),
+	}
+	ctx := SetPrincipal(context.Background(), p)
+	p.Roles[0] = "guest"
+
+	got, ok := GetPrincipal(ctx)
+	require.True(t, ok)
+	assert.True(t, got.HasRole("admin"))
+	assert.True(t, got.HasScope("project:read"))
+	assert.Equal(t, "u1", GetUserUuid(ctx))
+
+	decoded, err := DecodePrincipal(got.Encode())
+	require.NoError(t, err)
+	assert.Equal(t, got.Roles, decoded.Roles)
+	assert.Equal(t, got.RequestID, decoded.RequestID)
+	assert.WithinDuration(t, got.Deadline, decoded.Deadline, time.Second)
+
+	_, err = DecodePrincipal("%%%")
+	assert.ErrorIs(t, err, ErrInvalidPrincipal)
+
+	dctx, cancel := WithDeadline(ctx)
+	defer cancel()
+	deadline, ok := dctx.Deadline()
+	assert.True(t, ok)
+	assert.Equal(t, got.Deadline, deadline)
+}
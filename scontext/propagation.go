@@ -0,0 +1,166 @@
+package scontext
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	"time"
+)
+
+const (
+	baggageLanguage  = "scontext.lang"
+	baggageUserUuid  = "scontext.user"
+	baggagePrincipal = "scontext.principal"
+)
+
+// detachedContext keeps values of parent but never cancel
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (deadline time.Time, ok bool) {
+	return
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (d detachedContext) Value(key interface{}) interface{} {
+	return d.parent.Value(key)
+}
+
+// Detach new context with values of ctx but without its deadline and cancellation,
+// used for background goroutines that must outlive the request
+func Detach(ctx context.Context) context.Context {
+	return detachedContext{parent: ctx}
+}
+
+// BaggagePropagator propagate the language and user uuid of scontext by W3C baggage
+type BaggagePropagator struct {
+	// Principal also carry the Principal (roles, scopes, tenant, deadline).
+	// Baggage is an unsigned header anyone can send: enable it only between trusted internal hops,
+	// never on servers facing clients, or any caller can claim any role
+	Principal bool
+}
+
+var _ propagation.TextMapPropagator = BaggagePropagator{}
+
+// Inject set scontext values to carrier
+func (p BaggagePropagator) Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	propagation.Baggage{}.Inject(contextWithBaggage(ctx, p.Principal), carrier)
+}
+
+// Extract read scontext values from carrier
+func (p BaggagePropagator) Extract(parent context.Context, carrier propagation.TextMapCarrier) context.Context {
+	return fromBaggage(propagation.Baggage{}.Extract(parent, carrier), p.Principal)
+}
+
+// Fields header fields
+func (p BaggagePropagator) Fields() []string {
+	return propagation.Baggage{}.Fields()
+}
+
+// ContextWithBaggage copy the language and user uuid of scontext into the otel baggage of ctx
+func ContextWithBaggage(ctx context.Context) context.Context {
+	return contextWithBaggage(ctx, false)
+}
+
+// ContextWithPrincipalBaggage ContextWithBaggage with the Principal, for trusted internal hops only
+func ContextWithPrincipalBaggage(ctx context.Context) context.Context {
+	return contextWithBaggage(ctx, true)
+}
+
+// FromBaggage restore the language and user uuid of scontext from the otel baggage of ctx
+func FromBaggage(ctx context.Context) context.Context {
+	return fromBaggage(ctx, false)
+}
+
+// FromPrincipalBaggage FromBaggage with the Principal. The baggage is not signed, restore it only from trusted
+// internal hops, never from client requests
+func FromPrincipalBaggage(ctx context.Context) context.Context {
+	return fromBaggage(ctx, true)
+}
+
+func contextWithBaggage(ctx context.Context, principal bool) context.Context {
+	bag := baggage.FromContext(ctx)
+	set := func(key, val string) {
+		if val == "" {
+			return
+		}
+		m, err := baggage.NewMember(key, val)
+		if err != nil {
+			return
+		}
+		if b, err := bag.SetMember(m); err == nil {
+			bag = b
+		}
+	}
+	// 值只做一次 base64url 编码: 结果本身是合法的 baggage 值且 url 解码后不变,
+	// 不依赖 otel 对 NewMember 的值是否做 url 解码, 也避开 v1.14 解析时按解码后的值校验字符集
+	encode := func(val string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(val))
+	}
+	if lang, ok := ctx.Value(languageCtxTag{}).(string); ok && lang != "" {
+		set(baggageLanguage, encode(lang))
+	}
+	if uuid := GetUserUuid(ctx); uuid != "" {
+		set(baggageUserUuid, encode(uuid))
+	}
+	if p, ok := GetPrincipal(ctx); ok && principal {
+		// Encode 已经是 base64url
+		set(baggagePrincipal, p.Encode())
+	}
+	return baggage.ContextWithBaggage(ctx, bag)
+}
+
+func fromBaggage(ctx context.Context, principal bool) context.Context {
+	bag := baggage.FromContext(ctx)
+	get := func(key string) string {
+		v, err := base64.RawURLEncoding.DecodeString(bag.Member(key).Value())
+		if err != nil {
+			return ""
+		}
+		return string(v)
+	}
+	if v := bag.Member(baggagePrincipal).Value(); v != "" && principal {
+		if p, err := DecodePrincipal(v); err == nil {
+			ctx = SetPrincipal(ctx, p)
+		}
+	}
+	if v := get(baggageUserUuid); v != "" {
+		ctx = SetUserUuid(ctx, v)
+	}
+	if v := get(baggageLanguage); v != "" {
+		ctx = SetLanguage(ctx, v)
+	}
+	return ctx
+}
+
+// jobPropagator 任务负载由服务自身写入, 可以携带 Principal
+var jobPropagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, BaggagePropagator{Principal: true})
+
+// EncodeContext encode scontext values, the Principal included, and trace context for a job payload,
+// the payload must not come from clients
+func EncodeContext(ctx context.Context) ([]byte, error) {
+	carrier := propagation.MapCarrier{}
+	jobPropagator.Inject(ctx, carrier)
+	return json.Marshal(carrier)
+}
+
+// DecodeContext restore the values encoded by EncodeContext onto parent
+func DecodeContext(parent context.Context, data []byte) (context.Context, error) {
+	carrier := propagation.MapCarrier{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &carrier); err != nil {
+			return parent, err
+		}
+	}
+	return jobPropagator.Extract(parent, carrier), nil
+}
@@ -0,0 +1,75 @@
+package scontext
+
+import (
+	"context"
+	"github.com/olongfen/toolkit/consts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/propagation"
+	"testing"
+)
+
+func TestDetach(t *testing.T) {
+	parent, cancel := context.WithCancel(SetLanguage(context.Background(), consts.English))
+	ctx := Detach(parent)
+	cancel()
+	assert.Error(t, parent.Err())
+	assert.NoError(t, ctx.Err())
+	assert.Nil(t, ctx.Done())
+	assert.Equal(t, consts.English, GetLanguage(ctx))
+}
+
+func TestBaggagePropagator(t *testing.T) {
+	ctx := SetLanguage(context.Background(), consts.TraditionalChinese)
+	principal := Principal{UserUuid: "u 1", Roles: []string{"admin"}}
+	ctx = SetPrincipal(ctx, principal)
+
+	carrier := propagation.MapCarrier{}
+	BaggagePropagator{}.Inject(ctx, carrier)
+	assert.NotEmpty(t, carrier.Get("baggage"))
+	assert.NotContains(t, carrier.Get("baggage"), baggagePrincipal)
+
+	got := BaggagePropagator{}.Extract(context.Background(), carrier)
+	assert.Equal(t, consts.TraditionalChinese, GetLanguage(got))
+	assert.Equal(t, "u 1", GetUserUuid(got))
+	_, ok := GetPrincipal(got)
+	assert.False(t, ok)
+
+	// Principal 只在可信的内部调用间传递, 值只编码一次
+	carrier = propagation.MapCarrier{}
+	BaggagePropagator{Principal: true}.Inject(ctx, carrier)
+	assert.Contains(t, carrier.Get("baggage"), baggagePrincipal+"="+principal.Encode())
+	p, ok := GetPrincipal(BaggagePropagator{Principal: true}.Extract(context.Background(), carrier))
+	require.True(t, ok)
+	assert.True(t, p.HasRole("admin"))
+
+	// 默认不信任客户端伪造的 Principal
+	_, ok = GetPrincipal(BaggagePropagator{}.Extract(context.Background(), carrier))
+	assert.False(t, ok)
+}
+
+func TestEncodeContext(t *testing.T) {
+	ctx := SetUserUuid(SetLanguage(context.Background(), consts.English), "u1")
+	ctx = SetPrincipal(ctx, Principal{UserUuid: "u1", Scopes: []string{"jobs"}})
+	b, err := EncodeContext(ctx)
+	require.NoError(t, err)
+
+	got, err := DecodeContext(context.Background(), b)
+	require.NoError(t, err)
+	assert.Equal(t, consts.English, GetLanguage(got))
+	assert.Equal(t, "u1", GetUserUuid(got))
+	p, ok := GetPrincipal(got)
+	require.True(t, ok)
+	assert.Equal(t, []string{"jobs"}, p.Scopes)
+
+	_, err = DecodeContext(context.Background(), []byte("{"))
+	assert.Error(t, err)
+}
+
+func TestBaggageEscape(t *testing.T) {
+	const uuid = "u%1,a=b;c d"
+	carrier := propagation.MapCarrier{}
+	BaggagePropagator{}.Inject(SetUserUuid(context.Background(), uuid), carrier)
+	assert.Equal(t, baggageUserUuid+"=dSUxLGE9YjtjIGQ", carrier.Get("baggage"))
+	assert.Equal(t, uuid, GetUserUuid(BaggagePropagator{}.Extract(context.Background(), carrier)))
+}
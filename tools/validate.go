@@ -12,6 +12,7 @@ import (
 	"github.com/go-playground/validator/v10/translations/zh_tw"
 	"github.com/olongfen/toolkit/multi/xerror"
 	"github.com/olongfen/toolkit/scontext"
+	"strconv"
 	"strings"
 )
 
@@ -24,27 +25,55 @@ func init() {
 
 }
 
-// ValidateForm validate
+// ValidateForm validate form, the failed fields are returned as xerror.ValidateError of field -> description
+// in the language of ctx, use Validate to get the failed rules and their params as well
 func ValidateForm(ctx context.Context, form interface{}) error {
+	violations := validateForm(ctx, form)
+	if len(violations) > 0 {
+		return violations.ValidateError()
+	}
+	return nil
+}
+
+// Validate validate form, the failed fields are returned as xerror.FieldViolations with the rule, params
+// and the description in the language of ctx. It is not a xerror.ValidateError, match it by errors.As
+// instead of a type assertion or a type switch
+func Validate(ctx context.Context, form interface{}) error {
+	violations := validateForm(ctx, form)
+	if len(violations) > 0 {
+		return violations
+	}
+	return nil
+}
+
+func validateForm(ctx context.Context, form interface{}) xerror.FieldViolations {
 	var (
-		errs = xerror.ValidateError{}
+		errs xerror.FieldViolations
 	)
 	language := scontext.GetLanguage(ctx)
 	err := validate.Struct(form)
 	if err != nil {
 		for _, e := range err.(validator.ValidationErrors) {
-			//_err := &scontext.Error{}
-			//_err.Detail = e.Translate(translate(language))
-			//_err.Title = e.Title()
-			//_err.Failed = e.Field()
-			//_err.Value = e.Value()
-			errs[strings.ToLower(e.Field()[:1])+e.Field()[1:]] = e.Translate(translate(language))
+			errs = append(errs, xerror.FieldViolation{
+				Field:       strings.ToLower(e.Field()[:1]) + e.Field()[1:],
+				Rule:        e.Tag(),
+				Params:      params(e),
+				Description: e.Translate(translate(language)),
+			})
 		}
 	}
-	if len(errs) > 0 {
-		return errs
+	return errs
+}
+
+// params param of the rule keyed by the rule, e.g. {"max": 10}, numbers are kept as numbers
+func params(e validator.FieldError) xerror.Args {
+	if e.Param() == "" {
+		return nil
+	}
+	if n, err := strconv.Atoi(e.Param()); err == nil {
+		return xerror.Args{e.Tag(): n}
 	}
-	return nil
+	return xerror.Args{e.Tag(): e.Param()}
 }
 
 func translate(language string) ut.Translator {
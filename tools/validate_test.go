@@ -0,0 +1,38 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"github.com/olongfen/toolkit/consts"
+	"github.com/olongfen/toolkit/multi/xerror"
+	"github.com/olongfen/toolkit/scontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type form struct {
+	Name string `validate:"required"`
+	Age  int    `validate:"max=10"`
+}
+
+func TestValidate(t *testing.T) {
+	ctx := scontext.SetLanguage(context.Background(), consts.English)
+	err := ValidateForm(ctx, &form{Age: 11})
+	// ValidateForm 保持返回 ValidateError
+	validate, ok := err.(xerror.ValidateError)
+	require.True(t, ok)
+	assert.Equal(t, xerror.ValidateError{"name": "Name is a required field", "age": "Age must be 10 or less"}, validate)
+
+	err = Validate(ctx, &form{Age: 11})
+	var violations xerror.FieldViolations
+	require.True(t, errors.As(err, &violations))
+	assert.Equal(t, xerror.FieldViolations{
+		{Field: "name", Rule: "required", Description: "Name is a required field"},
+		{Field: "age", Rule: "max", Params: xerror.Args{"max": 10}, Description: "Age must be 10 or less"},
+	}, violations)
+	require.True(t, errors.As(err, &validate))
+
+	assert.NoError(t, ValidateForm(ctx, &form{Name: "tom"}))
+	assert.NoError(t, Validate(ctx, &form{Name: "tom"}))
+}
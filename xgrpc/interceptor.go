@@ -0,0 +1,150 @@
+package xgrpc
+
+import (
+	"context"
+	"github.com/olongfen/toolkit/consts"
+	"github.com/olongfen/toolkit/scontext"
+	"golang.org/x/text/language"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"io"
+	"strings"
+)
+
+// MetadataAcceptLanguage metadata key of the request language
+const MetadataAcceptLanguage = "accept-language"
+
+// Config server interceptor config
+type Config struct {
+	// Supported supported languages, the first one is default, default consts.SupportedLanguages
+	Supported []string
+	// ExposeErrors send err.Error() of unknown errors to clients, off by default.
+	// Messages of internal errors may carry secrets, e.g. "password=secret", enable it in development only
+	ExposeErrors bool
+}
+
+type server struct {
+	supported []string
+	matcher   language.Matcher
+	expose    bool
+}
+
+func newServer(config ...Config) *server {
+	var (
+		conf Config
+	)
+	if len(config) > 0 {
+		conf = config[0]
+	}
+	if len(conf.Supported) == 0 {
+		conf.Supported = consts.SupportedLanguages
+	}
+	s := &server{expose: conf.ExposeErrors}
+	tags := make([]language.Tag, 0, len(conf.Supported))
+	for _, v := range conf.Supported {
+		s.supported = append(s.supported, strings.ToLower(v))
+		tags = append(tags, language.Make(v))
+	}
+	s.matcher = language.NewMatcher(tags)
+	return s
+}
+
+// language negotiate accept-language of the incoming metadata, the first supported language when nothing matches
+func (s *server) language(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	tags, _, _ := language.ParseAcceptLanguage(strings.Join(md.Get(MetadataAcceptLanguage), ","))
+	if len(tags) > 0 {
+		if _, index, confidence := s.matcher.Match(tags...); confidence != language.No {
+			return s.supported[index]
+		}
+	}
+	return s.supported[0]
+}
+
+// convert status of err, messages of unknown errors are replaced by "Unknown" unless ExposeErrors
+func (s *server) convert(ctx context.Context, err error) error {
+	st := Status(err, scontext.GetLanguage(ctx))
+	if !s.expose && st.Code() == codes.Unknown && len(st.Details()) == 0 {
+		st = status.New(codes.Unknown, codes.Unknown.String())
+	}
+	return st.Err()
+}
+
+// UnaryServerInterceptor store the language of accept-language by scontext.SetLanguage and convert errors by Status,
+// unknown errors are sent as codes.Unknown without their message unless Config.ExposeErrors
+func UnaryServerInterceptor(config ...Config) grpc.UnaryServerInterceptor {
+	s := newServer(config...)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx = scontext.SetLanguage(ctx, s.language(ctx))
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return resp, s.convert(ctx, err)
+		}
+		return resp, nil
+	}
+}
+
+// StreamServerInterceptor stream version of UnaryServerInterceptor
+func StreamServerInterceptor(config ...Config) grpc.StreamServerInterceptor {
+	s := newServer(config...)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := scontext.SetLanguage(ss.Context(), s.language(ss.Context()))
+		if err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx}); err != nil {
+			return s.convert(ctx, err)
+		}
+		return nil
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// outgoingLanguage send the language of ctx as accept-language unless set
+func outgoingLanguage(ctx context.Context) context.Context {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	if len(md.Get(MetadataAcceptLanguage)) == 0 {
+		ctx = metadata.AppendToOutgoingContext(ctx, MetadataAcceptLanguage, scontext.GetLanguage(ctx))
+	}
+	return ctx
+}
+
+// UnaryClientInterceptor send the language of ctx as accept-language and restore errors by FromStatus
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if err := invoker(outgoingLanguage(ctx), method, req, reply, cc, opts...); err != nil {
+			return FromStatus(status.Convert(err))
+		}
+		return nil
+	}
+}
+
+// StreamClientInterceptor stream version of UnaryClientInterceptor
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		cs, err := streamer(outgoingLanguage(ctx), desc, cc, method, opts...)
+		if err != nil {
+			return nil, FromStatus(status.Convert(err))
+		}
+		return &clientStream{ClientStream: cs}, nil
+	}
+}
+
+type clientStream struct {
+	grpc.ClientStream
+}
+
+func (s *clientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err == nil || err == io.EOF {
+		return err
+	}
+	return FromStatus(status.Convert(err))
+}
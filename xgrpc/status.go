@@ -0,0 +1,277 @@
+// Package xgrpc carry xerror errors across grpc by google.rpc statuses and localize them by accept-language
+package xgrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/olongfen/toolkit/multi/xerror"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/runtime/protoiface"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ErrorInfo metadata keys carrying the biz error across the boundary
+const (
+	MetadataCode     = "code"
+	MetadataLanguage = "language"
+	MetadataArgs     = "args"
+	// MetadataKind "validate" or "db" for xerror.ValidateError and xerror.DBErrorResponse
+	MetadataKind = "kind"
+	// MetadataFieldCode prefix of the code of every field of xerror.DBErrorResponse
+	MetadataFieldCode = "code."
+
+	kindValidate = "validate"
+	kindDB       = "db"
+)
+
+// CodeOf grpc code of biz error code, the GRPCCode of its xerror.Definition, codes.Unknown when not registered
+func CodeOf(code int) codes.Code {
+	if def, ok := xerror.Lookup(code); ok && def.GRPCCode != 0 {
+		return codes.Code(def.GRPCCode)
+	}
+	return codes.Unknown
+}
+
+// Status convert err to status with messages in lang, biz errors carry their code, args and details,
+// errors already carrying a status and context errors keep their code, other errors are codes.Unknown
+// with err.Error() as the message, the server interceptors hide it
+func Status(err error, lang string) *status.Status {
+	if err == nil {
+		return nil
+	}
+	if st, ok := status.FromError(err); ok {
+		return st
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err)
+	}
+	var (
+		validate xerror.ValidateError
+		db       xerror.DBErrorResponse
+	)
+	if e, ok := xerror.FromError(err); ok {
+		return bizStatus(e, lang, nil)
+	}
+	if errors.As(err, &validate) {
+		e := xerror.NewError(xerror.IllegalParameter, lang).WithDetails(validate.BadRequest())
+		return bizStatus(e, lang, map[string]string{MetadataKind: kindValidate})
+	}
+	if errors.As(err, &db) && len(db) > 0 {
+		return dbStatus(db, lang)
+	}
+	return status.New(codes.Unknown, err.Error())
+}
+
+func bizStatus(e xerror.BizError, lang string, metadata map[string]string) *status.Status {
+	var (
+		msg  = e.Localize(lang)
+		info = &errdetails.ErrorInfo{
+			Reason:   "CODE_" + strconv.Itoa(e.Code()),
+			Metadata: map[string]string{MetadataCode: strconv.Itoa(e.Code()), MetadataLanguage: lang},
+		}
+	)
+	if def, ok := xerror.Lookup(e.Code()); ok {
+		info.Reason, info.Domain = reason(def.Name), def.Namespace
+	}
+	for k, v := range metadata {
+		info.Metadata[k] = v
+	}
+	if args := e.Args(); args != nil {
+		if b, err := json.Marshal(args); err == nil {
+			info.Metadata[MetadataArgs] = string(b)
+		}
+	}
+	details := []protoiface.MessageV1{info, &errdetails.LocalizedMessage{Locale: lang, Message: msg}}
+	for _, d := range e.Details() {
+		if m := toProto(d); m != nil {
+			details = append(details, m)
+		}
+	}
+	st := status.New(CodeOf(e.Code()), msg)
+	if ret, err := st.WithDetails(details...); err == nil {
+		return ret
+	}
+	return st
+}
+
+// reason UPPER_SNAKE_CASE of the CamelCase name as google.rpc.ErrorInfo expects, e.g. RecordNotFound -> RECORD_NOT_FOUND,
+// HTTPTimeout -> HTTP_TIMEOUT
+func reason(name string) string {
+	var (
+		b     strings.Builder
+		runes = []rune(name)
+	)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && (!unicode.IsUpper(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// dbStatus code of the first field, every field is a violation of BadRequest
+func dbStatus(db xerror.DBErrorResponse, lang string) *status.Status {
+	var (
+		fields   = db.Fields()
+		metadata = map[string]string{MetadataKind: kindDB}
+	)
+	for _, field := range fields {
+		metadata[MetadataFieldCode+field] = strconv.Itoa(db[field].Code())
+	}
+	first := db[fields[0]]
+	return bizStatus(xerror.NewError(first.Code(), lang).WithDetails(db.BadRequest(lang)), lang, metadata)
+}
+
+// FromStatus restore the error converted by Status, statuses without biz code are returned as st.Err()
+func FromStatus(st *status.Status) error {
+	if st == nil || st.Code() == codes.OK {
+		return nil
+	}
+	var (
+		info      *errdetails.ErrorInfo
+		localized *errdetails.LocalizedMessage
+		details   xerror.Details
+	)
+	for _, v := range st.Details() {
+		switch d := v.(type) {
+		case *errdetails.ErrorInfo:
+			info = d
+		case *errdetails.LocalizedMessage:
+			// 第一个是 Status 添加的消息
+			if localized == nil {
+				localized = d
+				continue
+			}
+			details = append(details, xerror.LocalizedMessage{Locale: d.GetLocale(), Message: d.GetMessage()})
+		default:
+			if detail, ok := fromProto(d); ok {
+				details = append(details, detail)
+			}
+		}
+	}
+	if info == nil {
+		return st.Err()
+	}
+	code, err := strconv.Atoi(info.GetMetadata()[MetadataCode])
+	if err != nil {
+		return st.Err()
+	}
+	var (
+		metadata = info.GetMetadata()
+		lang     = metadata[MetadataLanguage]
+		msg      = st.Message()
+		args     xerror.Args
+	)
+	if localized != nil {
+		lang, msg = localized.GetLocale(), localized.GetMessage()
+	}
+	if s := metadata[MetadataArgs]; s != "" {
+		_ = json.Unmarshal([]byte(s), &args)
+	}
+	switch metadata[MetadataKind] {
+	case kindValidate:
+		ret := xerror.ValidateError{}
+		for _, v := range badRequestOf(details).FieldViolations {
+			ret[v.Field] = v.Description
+		}
+		return ret
+	case kindDB:
+		ret := xerror.DBErrorResponse{}
+		for _, v := range badRequestOf(details).FieldViolations {
+			c, err := strconv.Atoi(metadata[MetadataFieldCode+v.Field])
+			if err != nil {
+				c = code
+			}
+			ret[v.Field] = xerror.Restore(c, lang, v.Description, nil)
+		}
+		return ret
+	}
+	return xerror.Restore(code, lang, msg, args, details...)
+}
+
+func badRequestOf(details xerror.Details) xerror.BadRequest {
+	for _, d := range details {
+		if v, ok := d.(xerror.BadRequest); ok {
+			return v
+		}
+	}
+	return xerror.BadRequest{}
+}
+
+// toProto google.rpc detail of d, Rule and Params of field violations are not part of google.rpc.BadRequest and are dropped
+func toProto(d xerror.Detail) protoiface.MessageV1 {
+	switch v := d.(type) {
+	case xerror.BadRequest:
+		ret := &errdetails.BadRequest{}
+		for _, f := range v.FieldViolations {
+			ret.FieldViolations = append(ret.FieldViolations, &errdetails.BadRequest_FieldViolation{Field: f.Field, Description: f.Description})
+		}
+		return ret
+	case xerror.PreconditionFailure:
+		ret := &errdetails.PreconditionFailure{}
+		for _, p := range v.Violations {
+			ret.Violations = append(ret.Violations, &errdetails.PreconditionFailure_Violation{Type: p.Type, Subject: p.Subject, Description: p.Description})
+		}
+		return ret
+	case xerror.QuotaFailure:
+		ret := &errdetails.QuotaFailure{}
+		for _, q := range v.Violations {
+			ret.Violations = append(ret.Violations, &errdetails.QuotaFailure_Violation{Subject: q.Subject, Description: q.Description})
+		}
+		return ret
+	case xerror.RetryInfo:
+		return &errdetails.RetryInfo{RetryDelay: durationpb.New(v.RetryDelay)}
+	case xerror.Help:
+		ret := &errdetails.Help{}
+		for _, l := range v.Links {
+			ret.Links = append(ret.Links, &errdetails.Help_Link{Description: l.Description, Url: l.URL})
+		}
+		return ret
+	case xerror.LocalizedMessage:
+		return &errdetails.LocalizedMessage{Locale: v.Locale, Message: v.Message}
+	case xerror.ResourceInfo:
+		return &errdetails.ResourceInfo{ResourceType: v.ResourceType, ResourceName: v.ResourceName, Owner: v.Owner, Description: v.Description}
+	}
+	return nil
+}
+
+func fromProto(m interface{}) (xerror.Detail, bool) {
+	switch v := m.(type) {
+	case *errdetails.BadRequest:
+		ret := xerror.BadRequest{}
+		for _, f := range v.GetFieldViolations() {
+			ret.FieldViolations = append(ret.FieldViolations, xerror.FieldViolation{Field: f.GetField(), Description: f.GetDescription()})
+		}
+		return ret, true
+	case *errdetails.PreconditionFailure:
+		ret := xerror.PreconditionFailure{}
+		for _, p := range v.GetViolations() {
+			ret.Violations = append(ret.Violations, xerror.PreconditionViolation{Type: p.GetType(), Subject: p.GetSubject(), Description: p.GetDescription()})
+		}
+		return ret, true
+	case *errdetails.QuotaFailure:
+		ret := xerror.QuotaFailure{}
+		for _, q := range v.GetViolations() {
+			ret.Violations = append(ret.Violations, xerror.QuotaViolation{Subject: q.GetSubject(), Description: q.GetDescription()})
+		}
+		return ret, true
+	case *errdetails.RetryInfo:
+		return xerror.RetryInfo{RetryDelay: v.GetRetryDelay().AsDuration()}, true
+	case *errdetails.Help:
+		ret := xerror.Help{}
+		for _, l := range v.GetLinks() {
+			ret.Links = append(ret.Links, xerror.HelpLink{Description: l.GetDescription(), URL: l.GetUrl()})
+		}
+		return ret, true
+	case *errdetails.ResourceInfo:
+		return xerror.ResourceInfo{ResourceType: v.GetResourceType(), ResourceName: v.GetResourceName(), Owner: v.GetOwner(), Description: v.GetDescription()}, true
+	}
+	return nil, false
+}
@@ -0,0 +1,155 @@
+package xgrpc
+
+import (
+	"context"
+	"errors"
+	"github.com/olongfen/toolkit/consts"
+	"github.com/olongfen/toolkit/multi/xerror"
+	"github.com/olongfen/toolkit/scontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"testing"
+	"time"
+)
+
+// healthServer return the error of the requested service
+type healthServer struct {
+	grpc_health_v1.UnimplementedHealthServer
+	errs map[string]func(ctx context.Context) error
+}
+
+func (s *healthServer) Check(ctx context.Context, req *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	if fn, ok := s.errs[req.GetService()]; ok {
+		return nil, fn(ctx)
+	}
+	return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}, nil
+}
+
+func (s *healthServer) Watch(req *grpc_health_v1.HealthCheckRequest, stream grpc_health_v1.Health_WatchServer) error {
+	return s.errs[req.GetService()](stream.Context())
+}
+
+func dial(t *testing.T, errs map[string]func(ctx context.Context) error, config ...Config) grpc_health_v1.HealthClient {
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryServerInterceptor(config...)),
+		grpc.StreamInterceptor(StreamServerInterceptor(config...)),
+	)
+	grpc_health_v1.RegisterHealthServer(srv, &healthServer{errs: errs})
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, s string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor()),
+		grpc.WithStreamInterceptor(StreamClientInterceptor()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return grpc_health_v1.NewHealthClient(conn)
+}
+
+func TestInterceptors(t *testing.T) {
+	client := dial(t, map[string]func(ctx context.Context) error{
+		"biz": func(ctx context.Context) error {
+			return xerror.NewErrorContext(ctx, xerror.PreconditionFailed).WithDetails(xerror.RetryInfo{RetryDelay: 3 * time.Second})
+		},
+		"validate": func(ctx context.Context) error {
+			return xerror.ValidateError{"name": "name is required"}
+		},
+		"db": func(ctx context.Context) error {
+			return xerror.DBErrorResponse{"email": xerror.NewErrorContext(ctx, xerror.AlreadyExists)}
+		},
+		"unknown": func(ctx context.Context) error {
+			return errors.New("password=secret")
+		},
+	})
+	ctx := scontext.SetLanguage(context.Background(), consts.English)
+
+	_, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: "biz"})
+	e, ok := xerror.FromError(err)
+	require.True(t, ok)
+	assert.True(t, xerror.IsCode(err, xerror.PreconditionFailed))
+	assert.Equal(t, "resource has been modified, please reload and retry", e.Error())
+	assert.Equal(t, xerror.Details{xerror.RetryInfo{RetryDelay: 3 * time.Second}}, e.Details())
+
+	// accept-language 优先于 ctx 的语言
+	_, err = client.Check(metadata.AppendToOutgoingContext(ctx, MetadataAcceptLanguage, "zh-TW"), &grpc_health_v1.HealthCheckRequest{Service: "biz"})
+	assert.Equal(t, "資源已被修改,請刷新後重試", err.Error())
+
+	_, err = client.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: "validate"})
+	assert.Equal(t, xerror.ValidateError{"name": "name is required"}, err)
+
+	_, err = client.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: "db"})
+	var db xerror.DBErrorResponse
+	require.True(t, errors.As(err, &db))
+	assert.Equal(t, xerror.AlreadyExists, db["email"].Code())
+	assert.Equal(t, "already exists,duplicate creation is not allowed", db["email"].Error())
+
+	_, err = client.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: "unknown"})
+	assert.Equal(t, codes.Unknown, status.Code(err))
+	assert.NotContains(t, err.Error(), "secret")
+	exposed := dial(t, map[string]func(ctx context.Context) error{
+		"unknown": func(ctx context.Context) error {
+			return errors.New("password=secret")
+		},
+	}, Config{ExposeErrors: true})
+	_, err = exposed.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: "unknown"})
+	assert.Equal(t, "password=secret", status.Convert(err).Message())
+
+	stream, err := client.Watch(ctx, &grpc_health_v1.HealthCheckRequest{Service: "biz"})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.True(t, xerror.IsCode(err, xerror.PreconditionFailed))
+	assert.Equal(t, "resource has been modified, please reload and retry", err.Error())
+	assert.Equal(t, codes.FailedPrecondition, Status(err, consts.English).Code())
+}
+
+func TestStatus(t *testing.T) {
+	const unregistered = 40096
+	xerror.DefaultErrorMul.Set(unregistered, consts.English, "{{.Name}} is archived")
+	err := xerror.New(unregistered, xerror.Args{"Name": "report"})
+	st := Status(err, consts.English)
+	xerror.DefaultErrorMul.DeleteKey(unregistered)
+	assert.Equal(t, codes.Unknown, st.Code())
+	assert.Equal(t, "report is archived", st.Message())
+
+	// 本地没有翻译时使用服务端消息
+	e, ok := xerror.FromError(FromStatus(st))
+	require.True(t, ok)
+	assert.Equal(t, unregistered, e.Code())
+	assert.Equal(t, "report is archived", e.Error())
+	assert.Equal(t, xerror.Args{"Name": "report"}, e.Args())
+
+	assert.Equal(t, "CODE_40096", errorInfoOf(t, st).GetReason())
+	info := errorInfoOf(t, Status(xerror.NewError(xerror.RecordNotFound, consts.English), consts.English))
+	assert.Equal(t, "RECORD_NOT_FOUND", info.GetReason())
+	assert.Equal(t, "HTTP_TIMEOUT", reason("HTTPTimeout"))
+
+	assert.Equal(t, codes.NotFound, CodeOf(xerror.RecordNotFound))
+	assert.Equal(t, codes.DeadlineExceeded, Status(context.DeadlineExceeded, consts.English).Code())
+	assert.Nil(t, Status(nil, consts.English))
+	plain := status.Error(codes.Unavailable, "down")
+	assert.Equal(t, plain, FromStatus(status.Convert(plain)))
+}
+
+func errorInfoOf(t *testing.T, st *status.Status) *errdetails.ErrorInfo {
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok {
+			return info
+		}
+	}
+	t.Fatal("no ErrorInfo")
+	return nil
+}
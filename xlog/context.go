@@ -0,0 +1,27 @@
+package xlog
+
+import (
+	"context"
+	"github.com/olongfen/toolkit/scontext"
+	"go.uber.org/zap"
+)
+
+// WithContext add request id, trace id and user uuid of ctx to logger
+func WithContext(log *zap.Logger, ctx context.Context) *zap.Logger {
+	var (
+		fields []zap.Field
+	)
+	if v := scontext.GetRequestID(ctx); v != "" {
+		fields = append(fields, zap.String("request_id", v))
+	}
+	if v := scontext.GetTraceID(ctx); v != "" {
+		fields = append(fields, zap.String("trace_id", v))
+	}
+	if v := scontext.GetUserUuid(ctx); v != "" {
+		fields = append(fields, zap.String("user_uuid", v))
+	}
+	if len(fields) == 0 {
+		return log
+	}
+	return log.With(fields...)
+}
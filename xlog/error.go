@@ -0,0 +1,46 @@
+package xlog
+
+import (
+	"errors"
+	"github.com/olongfen/toolkit/consts"
+	"github.com/olongfen/toolkit/multi/xerror"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// OperatorLanguage language of biz error messages in logs, independent of the client language
+var OperatorLanguage = consts.English
+
+// Error error field, the first BizError in err chain is logged as code, message in OperatorLanguage,
+// fingerprint, cause and stack
+func Error(err error) zap.Field {
+	e, ok := xerror.FromError(err)
+	if !ok {
+		return zap.Error(err)
+	}
+	return zap.Object("error", bizError{e.WithLanguage(OperatorLanguage)})
+}
+
+// bizError 以结构化字段输出 code, message, fingerprint, cause 与调用栈
+type bizError struct {
+	xerror.BizError
+}
+
+func (e bizError) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddInt("code", e.Code())
+	enc.AddString("message", e.Error())
+	enc.AddString("fingerprint", e.Fingerprint())
+	if cause := errors.Unwrap(e.BizError); cause != nil {
+		enc.AddString("cause", cause.Error())
+	}
+	stack := e.Stack()
+	if len(stack) == 0 {
+		return nil
+	}
+	return enc.AddArray("stack", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+		for _, f := range stack {
+			arr.AppendString(f.String())
+		}
+		return nil
+	}))
+}
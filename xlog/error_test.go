@@ -0,0 +1,30 @@
+package xlog
+
+import (
+	"errors"
+	"github.com/olongfen/toolkit/consts"
+	"github.com/olongfen/toolkit/multi/xerror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+	"strings"
+	"testing"
+)
+
+func TestError(t *testing.T) {
+	e := xerror.NewError(xerror.Forbidden, consts.TraditionalChinese).WithError(errors.New("boom"))
+	enc := zapcore.NewMapObjectEncoder()
+	Error(e).AddTo(enc)
+	fields := enc.Fields["error"].(map[string]interface{})
+	assert.Equal(t, xerror.Forbidden, fields["code"])
+	assert.Equal(t, "permission denied", fields["message"])
+	assert.Equal(t, "boom", fields["cause"])
+	assert.Equal(t, e.Fingerprint(), fields["fingerprint"])
+	stack := fields["stack"].([]interface{})
+	require.Len(t, stack, len(e.Stack()))
+	assert.True(t, strings.HasPrefix(stack[0].(string), "github.com/olongfen/toolkit/xlog.TestError"), stack[0])
+
+	enc = zapcore.NewMapObjectEncoder()
+	Error(errors.New("plain")).AddTo(enc)
+	assert.Equal(t, "plain", enc.Fields["error"])
+}
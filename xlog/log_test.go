@@ -1,10 +1,17 @@
 package xlog
 
-import "testing"
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
 
 func TestNewProduceLogger(t *testing.T) {
-
-	l := NewProduceLogger()
+	dir := t.TempDir()
+	l := NewProduceLogger(Config{InfoFile: filepath.Join(dir, "server.log"), ErrorFile: filepath.Join(dir, "error.log")})
 	l.Info("aaaaaaaaaaaaaaaaaa")
-
+	b, err := os.ReadFile(filepath.Join(dir, "server.log"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), "aaaaaaaaaaaaaaaaaa")
 }
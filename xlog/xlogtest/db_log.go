@@ -0,0 +1,102 @@
+package xlogtest
+
+import (
+	"context"
+	"github.com/olongfen/toolkit/xlog"
+	"go.uber.org/zap/zaptest/observer"
+	"gorm.io/gorm/logger"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Query executed sql
+type Query struct {
+	SQL          string
+	RowsAffected int64
+	Err          error
+	Elapsed      time.Duration
+}
+
+type queryStore struct {
+	mu      sync.Mutex
+	queries []Query
+}
+
+// DBLog gorm logger that keeps every executed sql in memory
+type DBLog struct {
+	logger.Interface
+	store *queryStore
+}
+
+// NewDBLog new db log, the wrapped xlog.DBLog writes to the returned observed logs
+func NewDBLog() (*DBLog, *observer.ObservedLogs) {
+	zapLog, logs := NewLogger()
+	return &DBLog{
+		Interface: xlog.NewDBLog(zapLog),
+		store:     &queryStore{},
+	}, logs
+}
+
+// LogMode change log level, captured queries are shared with the receiver
+func (l *DBLog) LogMode(level logger.LogLevel) logger.Interface {
+	return &DBLog{
+		Interface: l.Interface.LogMode(level),
+		store:     l.store,
+	}
+}
+
+// Trace record sql then delegate to xlog.DBLog
+func (l *DBLog) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	sql, rows := fc()
+	l.store.mu.Lock()
+	l.store.queries = append(l.store.queries, Query{
+		SQL:          sql,
+		RowsAffected: rows,
+		Err:          err,
+		Elapsed:      time.Since(begin),
+	})
+	l.store.mu.Unlock()
+	l.Interface.Trace(ctx, begin, func() (string, int64) { return sql, rows }, err)
+}
+
+// Queries get captured queries
+func (l *DBLog) Queries() []Query {
+	l.store.mu.Lock()
+	defer l.store.mu.Unlock()
+	return append([]Query(nil), l.store.queries...)
+}
+
+// Reset clear captured queries
+func (l *DBLog) Reset() {
+	l.store.mu.Lock()
+	l.store.queries = nil
+	l.store.mu.Unlock()
+}
+
+// AssertQueried assert a query containing snippet was executed
+func AssertQueried(t TestingT, l *DBLog, snippet string) bool {
+	t.Helper()
+	queries := l.Queries()
+	for _, q := range queries {
+		if strings.Contains(q.SQL, snippet) {
+			return true
+		}
+	}
+	var b strings.Builder
+	for _, q := range queries {
+		b.WriteString("  " + q.SQL + "\n")
+	}
+	t.Errorf("no query containing %q, got:\n%s", snippet, b.String())
+	return false
+}
+
+// AssertQueryCount assert number of executed queries
+func AssertQueryCount(t TestingT, l *DBLog, n int) bool {
+	t.Helper()
+	if got := len(l.Queries()); got != n {
+		t.Errorf("expected %d queries, got %d", n, got)
+		return false
+	}
+	return true
+}
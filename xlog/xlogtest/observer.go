@@ -0,0 +1,93 @@
+// Package xlogtest provides in-memory loggers and assertion helpers for tests
+// that exercise code logging through xlog, so no ./logs files are created.
+package xlogtest
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"reflect"
+	"strings"
+)
+
+// TestingT subset of testing.TB used by the assertions
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// NewLogger new observable logger, entries at or above level are kept in memory
+func NewLogger(level ...zapcore.Level) (*zap.Logger, *observer.ObservedLogs) {
+	lvl := zapcore.DebugLevel
+	if len(level) > 0 {
+		lvl = level[0]
+	}
+	core, logs := observer.New(lvl)
+	return zap.New(core, zap.AddCaller()), logs
+}
+
+// AssertLogged assert an entry was logged at level with all the given fields
+func AssertLogged(t TestingT, logs *observer.ObservedLogs, level zapcore.Level, fields ...zap.Field) bool {
+	t.Helper()
+	for _, entry := range logs.FilterLevelExact(level).All() {
+		if hasFields(entry, fields) {
+			return true
+		}
+	}
+	t.Errorf("no %s entry with fields %s, got:\n%s", level, formatFields(fields), dump(logs))
+	return false
+}
+
+// AssertMessage assert an entry containing msg was logged at level
+func AssertMessage(t TestingT, logs *observer.ObservedLogs, level zapcore.Level, msg string) bool {
+	t.Helper()
+	if logs.FilterLevelExact(level).FilterMessageSnippet(msg).Len() > 0 {
+		return true
+	}
+	t.Errorf("no %s entry containing %q, got:\n%s", level, msg, dump(logs))
+	return false
+}
+
+// AssertNotLogged assert nothing was logged at level
+func AssertNotLogged(t TestingT, logs *observer.ObservedLogs, level zapcore.Level) bool {
+	t.Helper()
+	if logs.FilterLevelExact(level).Len() == 0 {
+		return true
+	}
+	t.Errorf("unexpected %s entries:\n%s", level, dump(logs.FilterLevelExact(level)))
+	return false
+}
+
+func hasFields(entry observer.LoggedEntry, fields []zap.Field) bool {
+	ctx := entry.ContextMap()
+	for _, f := range fields {
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		want, ok := enc.Fields[f.Key]
+		if !ok {
+			return false
+		}
+		got, ok := ctx[f.Key]
+		if !ok || !reflect.DeepEqual(got, want) {
+			return false
+		}
+	}
+	return true
+}
+
+func formatFields(fields []zap.Field) string {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return fmt.Sprintf("%v", enc.Fields)
+}
+
+func dump(logs *observer.ObservedLogs) string {
+	var b strings.Builder
+	for _, entry := range logs.All() {
+		b.WriteString(fmt.Sprintf("  [%s] %s %v\n", entry.Level, entry.Message, entry.ContextMap()))
+	}
+	return b.String()
+}
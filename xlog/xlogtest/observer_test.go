@@ -0,0 +1,53 @@
+package xlogtest
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"testing"
+	"time"
+)
+
+func TestAssertLogged(t *testing.T) {
+	l, logs := NewLogger()
+	l.Info("user login", zap.String("user", "u1"), zap.Int("attempt", 2))
+	AssertLogged(t, logs, zapcore.InfoLevel, zap.String("user", "u1"))
+	AssertLogged(t, logs, zapcore.InfoLevel, zap.String("user", "u1"), zap.Int("attempt", 2))
+	AssertMessage(t, logs, zapcore.InfoLevel, "login")
+	AssertNotLogged(t, logs, zapcore.ErrorLevel)
+
+	mock := &fakeT{}
+	assert.False(t, AssertLogged(mock, logs, zapcore.InfoLevel, zap.String("user", "u2")))
+	assert.False(t, AssertLogged(mock, logs, zapcore.WarnLevel, zap.String("user", "u1")))
+	assert.Equal(t, 2, mock.errors)
+}
+
+type fakeT struct {
+	errors int
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, args ...interface{}) {
+	f.errors++
+}
+
+func TestDBLog(t *testing.T) {
+	l, _ := NewDBLog()
+	silent := l.LogMode(1)
+	l.Trace(context.Background(), time.Now(), func() (string, int64) {
+		return `SELECT * FROM "users" WHERE id = 1`, 1
+	}, nil)
+	silent.Trace(context.Background(), time.Now(), func() (string, int64) {
+		return `DELETE FROM "users" WHERE id = 1`, -1
+	}, errors.New("boom"))
+
+	AssertQueryCount(t, l, 2)
+	AssertQueried(t, l, `FROM "users"`)
+	AssertQueried(t, l, "DELETE")
+	assert.EqualError(t, l.Queries()[1].Err, "boom")
+	l.Reset()
+	AssertQueryCount(t, l, 0)
+}